@@ -19,6 +19,7 @@ for details.
 package df
 
 import (
+	"context"
 	_ "embed"
 	"fmt"
 	"iter"
@@ -37,6 +38,9 @@ type DF interface {
 	// By creates a new DF that groups the source DF by the columns listed in groupBy and calculates fns on the groups.
 	By(groupBy string, fns ...string) (DF, error)
 
+	// ByParams is By with values for the parameters (e.g. @rate) in fns.
+	ByParams(groupBy string, params map[string]any, fns ...string) (DF, error)
+
 	// Categorical creates a categorical column
 	//	colName    - name of the source column
 	//	catMap     - optionally supply a category map of source value -> category level
@@ -83,6 +87,9 @@ type DF interface {
 
 	// Where returns a DF subset according to condition.
 	Where(condition string) (DF, error)
+
+	// WhereParams is Where with values for the parameters (e.g. @rate) in condition.
+	WhereParams(condition string, params map[string]any) (DF, error)
 }
 
 type DC interface {
@@ -101,6 +108,9 @@ type DC interface {
 	// ColumnTypes returns the types of columns.  If cols is nil, returns the types for all columns.
 	ColumnTypes(cols ...string) ([]DataTypes, error)
 
+	// Context returns the context for database queries and long calculations.
+	Context() context.Context
+
 	// Core returns itself.
 	Core() *DFcore
 
@@ -131,6 +141,8 @@ type DFcore struct {
 
 	appFuncs Fns
 
+	ctx context.Context
+
 	dlct *Dialect
 
 	sourceDF *DFcore
@@ -181,11 +193,40 @@ func NewDFcore(cols []Column, opts ...DFopt) (df *DFcore, err error) {
 	return outDF, nil
 }
 
+// OptsContext returns the context set by a DFcontext option in opts, or context.Background() if there is none.
+// It is for constructors that query the database before the DF the opts are for exists.  Errors from the other
+// options are ignored here; they are reported when the options are applied to the DF.
+func OptsContext(opts ...DFopt) context.Context {
+	df := &DFcore{}
+	for _, opt := range opts {
+		_ = opt(df)
+	}
+
+	return df.Context()
+}
+
 // *********** Setters ***********
 
 // DFopt functions are used to set DFcore options
 type DFopt func(df DC) error
 
+// DFcontext sets the context used for database queries and to cancel long calculations.
+func DFcontext(ctx context.Context) DFopt {
+	return func(df DC) error {
+		if df == nil {
+			return fmt.Errorf("nil dataframe to DFcontext")
+		}
+
+		if ctx == nil {
+			return fmt.Errorf("nil context to DFcontext")
+		}
+
+		df.Core().ctx = ctx
+
+		return nil
+	}
+}
+
 func DFdialect(d *Dialect) DFopt {
 	return func(df DC) error {
 		if df == nil {
@@ -328,10 +369,20 @@ func (df *DFcore) Copy() *DFcore {
 
 	outDF, _ = NewDFcore(cols,
 		DFdialect(df.Dialect()), DFsetSourceDF(df.SourceDF()), DFsetFns(df.Fns()))
+	outDF.ctx = df.ctx
 
 	return outDF
 }
 
+// Context returns the context set by DFcontext.  The default is context.Background().
+func (df *DFcore) Context() context.Context {
+	if df.ctx == nil {
+		return context.Background()
+	}
+
+	return df.ctx
+}
+
 func (df *DFcore) Core() *DFcore {
 	return df
 }
@@ -432,5 +483,6 @@ type HasIter interface {
 // type HasDQdlct restricts to types that can access a DB
 type HasMQdlct interface {
 	MakeQuery(colNames ...string) string
+	MakeQueryE(colNames ...string) (string, error)
 	Dialect() *Dialect
 }
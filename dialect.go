@@ -1,12 +1,24 @@
 package df
 
 import (
+	"context"
 	"database/sql"
 	_ "embed"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"iter"
 	"math"
+	"reflect"
 	"strings"
 	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/jackc/pgx"
+	"github.com/jackc/pgx/stdlib"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var (
@@ -64,6 +76,21 @@ var (
 	chFunctions string
 	//go:embed skeletons/postgres/functions.txt
 	pgFunctions string
+
+	//go:embed skeletons/clickhouse/tables.txt
+	chTables string
+	//go:embed skeletons/postgres/tables.txt
+	pgTables string
+
+	//go:embed skeletons/clickhouse/describe.txt
+	chDescribe string
+	//go:embed skeletons/postgres/describe.txt
+	pgDescribe string
+
+	//go:embed skeletons/clickhouse/stats.txt
+	chStats string
+	//go:embed skeletons/postgres/stats.txt
+	pgStats string
 )
 
 // supported databases
@@ -90,10 +117,23 @@ type Dialect struct {
 	exists     string
 	existsTemp string
 	seq        string
+	tables     string
+	describe   string
+	stats      string
 
 	fields string // skeleton for defining a field in a CREATE statement
 
-	bufSize int // size of the buffer to use for an INSERT (in MB)
+	bufSize   int // size of the buffer to use for an INSERT (in MB)
+	fetchSize int // rows to fetch at a time when streaming a query. If 0, the driver default is used.
+
+	progress     func(tableName string, rows int, done bool) // reports the progress of Save
+	progressRows int                                         // how often to call progress
+
+	columnTypes map[string]DataTypes // DataTypes to use for specific fields, regardless of the DB type
+	fallback    DataTypes            // DataTypes to use for DB types with no df counterpart
+
+	logger func(ev *QueryEvent) // called after each statement is run
+	tracer trace.Tracer         // if not nil, each statement is run in a span
 
 	functions Fmap // functions for the parser
 
@@ -103,6 +143,15 @@ type Dialect struct {
 	defaultDate   time.Time // value of date to use if a value is null
 }
 
+// QueryEvent describes a statement run by a *Dialect.  It is passed to the DialectLogger function.
+type QueryEvent struct {
+	Op       string        // Dialect operation that ran the statement, e.g. Load, RowCount, Create
+	SQL      string        // statement run
+	Duration time.Duration // time to run the statement
+	Rows     int           // rows loaded, counted or affected; -1 if not known
+	Err      error         // error, if any
+}
+
 // NewDialect creates a *Dialect to manage DB access.
 func NewDialect(dialect string, db *sql.DB, opts ...DialectOpt) (*Dialect, error) {
 	dialect = strings.ToLower(dialect)
@@ -114,6 +163,7 @@ func NewDialect(dialect string, db *sql.DB, opts ...DialectOpt) (*Dialect, error
 		defaultFloat:  math.MaxFloat64,
 		defaultDate:   time.Date(1960, 1, 1, 0, 0, 0, 0, time.UTC),
 		defaultString: "",
+		fallback:      DTunknown,
 	}
 
 	var types string
@@ -121,11 +171,13 @@ func NewDialect(dialect string, db *sql.DB, opts ...DialectOpt) (*Dialect, error
 	case ch:
 		d.create, d.createTemp, d.fields, d.dropIf, d.insert, d.exists = chCreate, chCreateTemp, chFields, chDropIf, chInsert, chExists
 		d.existsTemp, d.seq, d.interp = chExistsTemp, chSeq, chInterp
+		d.tables, d.describe, d.stats = chTables, chDescribe, chStats
 		types = chTypes
 		d.functions = LoadFunctions(chFunctions)
 	case pg:
 		d.create, d.createTemp, d.fields, d.dropIf, d.insert, d.exists = pgCreate, pgCreateTemp, pgFields, pgDropIf, pgInsert, pgExists
 		d.existsTemp, d.seq, d.interp = pgExistsTemp, pgSeq, pgInterp
+		d.tables, d.describe, d.stats = pgTables, pgDescribe, pgStats
 		types = pgTypes
 		d.functions = LoadFunctions(pgFunctions)
 	default:
@@ -177,6 +229,91 @@ func DialectBuffSize(bufMB int) DialectOpt {
 	}
 }
 
+// DialectFetchSize sets the number of rows fetched at a time when a query is streamed (e.g. by Stream and
+// Load).  Postgres fetches the rows from a server-side cursor.  ClickHouse sets the block size of the query.
+func DialectFetchSize(rows int) DialectOpt {
+	return func(d *Dialect) error {
+		if rows <= 0 {
+			return fmt.Errorf("bad fetch size in Dialect")
+		}
+
+		d.fetchSize = rows
+
+		return nil
+	}
+}
+
+// DialectProgress sets a function that reports the progress of Save. It is called every "every" rows
+// and when the save is done.
+func DialectProgress(every int, progress func(tableName string, rows int, done bool)) DialectOpt {
+	return func(d *Dialect) error {
+		if every <= 0 {
+			return fmt.Errorf("bad progress interval in Dialect")
+		}
+
+		d.progress, d.progressRows = progress, every
+
+		return nil
+	}
+}
+
+// DialectColumnType loads fieldName as type dt, whatever its DB type.  Values are converted to dt.
+func DialectColumnType(fieldName string, dt DataTypes) DialectOpt {
+	return func(d *Dialect) error {
+		if dt != DTfloat && dt != DTint && dt != DTstring && dt != DTdate {
+			return fmt.Errorf("cannot load field %s as %s", fieldName, dt)
+		}
+
+		if d.columnTypes == nil {
+			d.columnTypes = make(map[string]DataTypes)
+		}
+
+		d.columnTypes[fieldName] = dt
+
+		return nil
+	}
+}
+
+// DialectFallback sets the type used for fields whose DB type has no counterpart in df. By default, these
+// fields are an error.
+func DialectFallback(dt DataTypes) DialectOpt {
+	return func(d *Dialect) error {
+		if dt != DTfloat && dt != DTint && dt != DTstring && dt != DTdate {
+			return fmt.Errorf("bad fallback type %s in Dialect", dt)
+		}
+
+		d.fallback = dt
+
+		return nil
+	}
+}
+
+// DialectLogger sets a function that is called after every statement the Dialect runs.
+func DialectLogger(logger func(ev *QueryEvent)) DialectOpt {
+	return func(d *Dialect) error {
+		if logger == nil {
+			return fmt.Errorf("nil logger in Dialect")
+		}
+
+		d.logger = logger
+
+		return nil
+	}
+}
+
+// DialectTracer runs every statement the Dialect issues in an OpenTelemetry span created by tracer.
+func DialectTracer(tracer trace.Tracer) DialectOpt {
+	return func(d *Dialect) error {
+		if tracer == nil {
+			return fmt.Errorf("nil tracer in Dialect")
+		}
+
+		d.tracer = tracer
+
+		return nil
+	}
+}
+
 // DialectDefaultDate sets the default date to use if a date is null.  Default is 1/1/1960.
 func DialectDefaultDate(year, mon, day int) DialectOpt {
 	return func(d *Dialect) error {
@@ -285,76 +422,164 @@ func (d *Dialect) Close() error {
 	return d.db.Close()
 }
 
-// Convert converts val to the corresponding datatype used by df.
-// assign assigns the indx vector of v to be val
+// Convert converts val to the corresponding datatype used by df. It panics if val's type is not supported.
 func (d *Dialect) Convert(val any) any {
+	var (
+		x any
+		e error
+	)
+	if x, e = d.ConvertE(val); e != nil {
+		panic(e)
+	}
+
+	return x
+}
+
+// ConvertE converts val to the corresponding datatype used by df.
+func (d *Dialect) ConvertE(val any) (any, error) {
 	switch x := val.(type) {
 	case float32:
-		return float64(x)
+		return float64(x), nil
 	case float64:
-		return x
+		return x, nil
 	case *float32:
-		return float64(*x)
+		return float64(*x), nil
 	case *float64:
-		return *x
+		return *x, nil
 	case *uint:
-		return int(*x)
+		return int(*x), nil
 	case *uint8:
-		return int(*x)
+		return int(*x), nil
 	case *uint16:
-		return int(*x)
+		return int(*x), nil
 	case *uint32:
-		return int(*x)
+		return int(*x), nil
 	case *uint64:
-		return int(*x)
+		return int(*x), nil
 
 	case uint:
-		return int(x)
+		return int(x), nil
 	case uint8:
-		return int(x)
+		return int(x), nil
 	case uint16:
-		return int(x)
+		return int(x), nil
 	case uint32:
-		return int(x)
+		return int(x), nil
 	case uint64:
-		return int(x)
+		return int(x), nil
 
 	case *int:
-		return int(*x)
+		return int(*x), nil
 	case *int8:
-		return int(*x)
+		return int(*x), nil
 	case *int16:
-		return int(*x)
+		return int(*x), nil
 	case *int32:
-		return int(*x)
+		return int(*x), nil
 	case *int64:
-		return int(*x)
+		return int(*x), nil
 
 	case int:
-		return int(x)
+		return int(x), nil
 	case int8:
-		return int(x)
+		return int(x), nil
 	case int16:
-		return int(x)
+		return int(x), nil
 	case int32:
-		return int(x)
+		return int(x), nil
 	case int64:
-		return int(x)
+		return int(x), nil
 
 	case string:
-		return x
+		return x, nil
 	case *string:
-		return *x
+		return *x, nil
 	case time.Time:
-		return x
+		return x, nil
 	case *time.Time:
-		return *x
-	default:
-		panic(fmt.Errorf("unsupported data type in dialect.Load"))
+		return *x, nil
+	case bool:
+		if x {
+			return 1, nil
+		}
+
+		return 0, nil
+	case []byte:
+		return string(x), nil
+	}
+
+	// decimals
+	if x, ok := val.(interface{ InexactFloat64() float64 }); ok {
+		return x.InexactFloat64(), nil
+	}
+
+	// UUIDs, IP addresses, etc.
+	if x, ok := val.(fmt.Stringer); ok {
+		return x.String(), nil
+	}
+
+	rv := reflect.ValueOf(val)
+	switch rv.Kind() {
+	case reflect.Pointer:
+		if !rv.IsNil() {
+			return d.ConvertE(rv.Elem().Interface())
+		}
+	case reflect.Slice, reflect.Array, reflect.Map:
+		// arrays, maps and tuples become JSON strings
+		if b, e := json.Marshal(val); e == nil {
+			return string(b), nil
+		}
+	}
+
+	return nil, fmt.Errorf("unsupported data type %T", val)
+}
+
+// ConvertTo converts val to type dt.  Nulls become the Dialect default of dt.
+func (d *Dialect) ConvertTo(val any, dt DataTypes) (any, error) {
+	// categorical values are stored as ints
+	if dt == DTcategorical {
+		dt = DTint
+	}
+
+	if isNil(val) {
+		switch dt {
+		case DTfloat:
+			return d.defaultFloat, nil
+		case DTint:
+			return d.defaultInt, nil
+		case DTstring:
+			return d.defaultString, nil
+		case DTdate:
+			return d.defaultDate, nil
+		}
+	}
+
+	var (
+		x any
+		e error
+	)
+	if x, e = d.ConvertE(val); e != nil {
+		return nil, e
+	}
+
+	if WhatAmI(x) == dt {
+		return x, nil
+	}
+
+	var ok bool
+	if x, ok = toDataType(x, dt); !ok {
+		return nil, fmt.Errorf("cannot convert %v to %s", val, dt)
 	}
+
+	return x, nil
+}
+
+// Create is CreateContext with a background context.
+func (d *Dialect) Create(tableName, orderBy string, fields []string, types []DataTypes, overwrite, temporary bool, options ...string) error {
+	return d.CreateContext(context.Background(), tableName, orderBy, fields, types, overwrite, temporary, options...)
 }
 
-// Create creates a table.
+// CreateContext creates a table.
 //
 //	tableName  - name of the table to create
 //	orderBy    - comma-separated list of fields to form the key (order)
@@ -363,8 +588,16 @@ func (d *Dialect) Convert(val any) any {
 //	overwrite  - if true, overwrite existing table
 //	temporary  - create a temp table
 //	options    - are in key:value format and are meant to replace placeholders in create.txt
-func (d *Dialect) Create(tableName, orderBy string, fields []string, types []DataTypes, overwrite, temporary bool, options ...string) error {
-	if d.Exists(tableName) && !overwrite {
+func (d *Dialect) CreateContext(ctx context.Context, tableName, orderBy string, fields []string, types []DataTypes, overwrite, temporary bool, options ...string) error {
+	var (
+		exist bool
+		e0    error
+	)
+	if exist, e0 = d.tableExists(ctx, tableName); e0 != nil {
+		return e0
+	}
+
+	if exist && !overwrite {
 		return fmt.Errorf("table %s exists", tableName)
 	}
 
@@ -414,7 +647,7 @@ func (d *Dialect) Create(tableName, orderBy string, fields []string, types []Dat
 		return fmt.Errorf("create still has placeholders: %s", create)
 	}
 
-	_, e := d.db.Exec(create)
+	_, e := d.exec(ctx, "Create", create)
 
 	return e
 }
@@ -423,23 +656,87 @@ func (d *Dialect) DB() *sql.DB {
 	return d.db
 }
 
+// Describe is DescribeContext with a background context.
+func (d *Dialect) Describe(tableName string) (fieldNames []string, fieldTypes []DataTypes, e error) {
+	return d.DescribeContext(context.Background(), tableName)
+}
+
+// DescribeContext returns the names and types of the columns of tableName.  Columns whose DB type has no
+// counterpart in df are DTunknown.
+func (d *Dialect) DescribeContext(ctx context.Context, tableName string) (fieldNames []string, fieldTypes []DataTypes, e error) {
+	schema, table := d.tableParts(tableName)
+	qry := strings.ReplaceAll(d.describe, "?Schema", d.schemaSQL(schema))
+	qry = strings.ReplaceAll(qry, "?Table", d.literal(table))
+
+	scan := func(rows *sql.Rows) error {
+		var name, dbType string
+		if ex := rows.Scan(&name, &dbType); ex != nil {
+			return ex
+		}
+
+		fieldNames = append(fieldNames, name)
+		fieldTypes = append(fieldTypes, dtFromDB(dbType))
+
+		return nil
+	}
+
+	if e = d.query(ctx, "Describe", qry, scan); e != nil {
+		return nil, nil, e
+	}
+
+	if fieldNames == nil {
+		return nil, nil, fmt.Errorf("table %s not found", tableName)
+	}
+
+	return fieldNames, fieldTypes, nil
+}
+
 func (d *Dialect) DialectName() string {
 	return d.dialect
 }
 
+// DropTable is DropTableContext with a background context.
 func (d *Dialect) DropTable(tableName string) error {
-	if !d.Exists(tableName) {
-		return nil
+	return d.DropTableContext(context.Background(), tableName)
+}
+
+// DropTableContext drops tableName if it exists.
+func (d *Dialect) DropTableContext(ctx context.Context, tableName string) error {
+	if exist, e0 := d.tableExists(ctx, tableName); e0 != nil || !exist {
+		return e0
 	}
 
 	qry := strings.ReplaceAll(d.dropIf, "?TableName", tableName)
-	_, e := d.DB().Exec(qry)
+	_, e := d.exec(ctx, "DropTable", qry)
 
 	return e
 }
 
-// Exists returns true if tableName exists on the db.
+// Exists returns true if tableName exists on the db.  It panics if the query fails; ExistsE returns the error.
 func (d *Dialect) Exists(tableName string) bool {
+	var (
+		exist bool
+		e     error
+	)
+	if exist, e = d.ExistsE(tableName); e != nil {
+		panic(e)
+	}
+
+	return exist
+}
+
+// ExistsE is ExistsContext with a background context.
+func (d *Dialect) ExistsE(tableName string) (bool, error) {
+	return d.ExistsContext(context.Background(), tableName)
+}
+
+// ExistsContext returns true if tableName exists on the db.
+func (d *Dialect) ExistsContext(ctx context.Context, tableName string) (bool, error) {
+	return d.tableExists(ctx, tableName)
+}
+
+// tableExists returns true if tableName exists on the db.
+func (d *Dialect) tableExists(ctx context.Context, tableName string) (bool, error) {
 	var (
 		res *sql.Rows
 		e   error
@@ -454,8 +751,10 @@ func (d *Dialect) Exists(tableName string) bool {
 
 		qry = strings.ReplaceAll(qry, "?TableName", tableName)
 
-		if res, e = d.DB().Query(qry); e != nil {
-			panic(e)
+		qctx, done := d.observe(ctx, "Exists", qry)
+		if res, e = d.db.QueryContext(qctx, qry); e != nil {
+			done(-1, e)
+			return false, e
 		}
 
 		defer func() { _ = res.Close() }()
@@ -463,22 +762,51 @@ func (d *Dialect) Exists(tableName string) bool {
 		var exist any
 		res.Next()
 		if ex := res.Scan(&exist); ex != nil {
-			panic(ex)
+			done(-1, ex)
+			return false, ex
 		}
 
+		done(1, nil)
+
 		switch x := exist.(type) {
 		case bool:
 			if x {
-				return true
+				return true, nil
 			} // for pg
 		case uint8:
 			if x == 1 {
-				return true
+				return true, nil
 			} // for ch
 		}
 	}
 
-	return false
+	return false, nil
+}
+
+// Explain is ExplainContext with a background context.
+func (d *Dialect) Explain(qry string) (string, error) {
+	return d.ExplainContext(context.Background(), qry)
+}
+
+// ExplainContext returns the database's EXPLAIN output for qry.
+func (d *Dialect) ExplainContext(ctx context.Context, qry string) (string, error) {
+	var lines []string
+	scan := func(rows *sql.Rows) error {
+		var line string
+		if e := rows.Scan(&line); e != nil {
+			return e
+		}
+
+		lines = append(lines, line)
+
+		return nil
+	}
+
+	if e := d.query(ctx, "Explain", "EXPLAIN "+qry, scan); e != nil {
+		return "", e
+	}
+
+	return strings.Join(lines, "\n"), nil
 }
 
 // Functions returns a map of functions for the parser.
@@ -492,21 +820,31 @@ func (d *Dialect) Global(sourceSQL, colSQL string) string {
 	return fmt.Sprintf("(WITH global AS (%s) SELECT (%s) FROM global)", sourceSQL, colSQL)
 }
 
-// Insert executes an insert query
+// Insert is InsertContext with a background context.
 func (d *Dialect) Insert(tableName, makeQuery, fields string) error {
+	return d.InsertContext(context.Background(), tableName, makeQuery, fields)
+}
+
+// InsertContext executes an insert query
+func (d *Dialect) InsertContext(ctx context.Context, tableName, makeQuery, fields string) error {
 	qry := strings.Replace(d.insert, "?TableName", tableName, 1)
 	qry = strings.Replace(qry, "?MakeQuery", makeQuery, 1)
 	qry = strings.Replace(qry, "?Fields", fields, 1)
 
-	_, e := d.db.Exec(qry)
+	_, e := d.exec(ctx, "Insert", qry)
 
 	return e
 }
 
-// InsertValues inserts values into tableName
+// InsertValues is InsertValuesContext with a background context.
 func (d *Dialect) InsertValues(tableName string, values []byte) error {
+	return d.InsertValuesContext(context.Background(), tableName, values)
+}
+
+// InsertValuesContext inserts values into tableName
+func (d *Dialect) InsertValuesContext(ctx context.Context, tableName string, values []byte) error {
 	qry := fmt.Sprintf("INSERT INTO %s VALUES ", tableName) + string(values)
-	_, e := d.db.Exec(qry)
+	_, e := d.exec(ctx, "InsertValues", qry)
 
 	return e
 }
@@ -523,61 +861,479 @@ func (d *Dialect) Interp(sourceSQL, interpSQL, xSfield, xIfield, yField, outFiel
 	return qry
 }
 
-// IterSave saves the data represented by df into tableName
+// IterSave is IterSaveContext with a background context.
 func (d *Dialect) IterSave(tableName string, df HasIter) error {
-	const (
-		bSep   = byte(',')
-		bOpen  = byte('(')
-		bClose = byte(')')
+	return d.IterSaveContext(context.Background(), tableName, df)
+}
+
+// IterSaveContext saves the data represented by df into tableName.  Postgres uses COPY FROM STDIN.  ClickHouse sends
+// batches of about bufSize MB.
+func (d *Dialect) IterSaveContext(ctx context.Context, tableName string, df HasIter) error {
+	if d.DialectName() == ch {
+		return d.batchSave(ctx, tableName, df)
+	}
+
+	var (
+		fieldNames []string
+		e          error
 	)
+	if fieldNames, _, e = saveFields(df); e != nil {
+		return e
+	}
 
-	var buffer []byte
+	// COPY needs a pgx connection
+	if e1 := d.copySave(ctx, tableName, fieldNames, df); !errors.Is(e1, stdlib.ErrNotPgx) {
+		return e1
+	}
+
+	return d.insertSave(ctx, tableName, df)
+}
+
+// batchSave appends the rows to ClickHouse batches.  With database/sql, Prepare starts a batch, Exec appends
+// a row and Commit sends the batch.
+func (d *Dialect) batchSave(ctx context.Context, tableName string, df HasIter) error {
+	var (
+		tx   *sql.Tx
+		stmt *sql.Stmt
+		done func(rows int, e error) // logs the batch
+	)
+	qry := fmt.Sprintf("INSERT INTO %s", tableName)
 	bsize := d.bufSize * 1024 * 1024
+	size, rows, batchRows := 0, 0, 0
+
+	// abort rolls back the batch
+	abort := func(e error) error {
+		_ = tx.Rollback()
+		done(batchRows, e)
+		return e
+	}
 
 	for _, row := range df.AllRows() {
-		if buffer != nil {
-			buffer = append(buffer, bSep)
+		if tx == nil {
+			var (
+				bctx context.Context
+				e    error
+			)
+			bctx, done = d.observe(ctx, "Save", qry)
+			if tx, e = d.db.BeginTx(bctx, nil); e != nil {
+				done(-1, e)
+				return e
+			}
+
+			if stmt, e = tx.PrepareContext(bctx, qry); e != nil {
+				return abort(e)
+			}
 		}
 
-		buffer = append(buffer, bOpen)
-		for ind := range len(row) {
-			var x any
-			switch xx := row[ind].(type) {
-			case int, float64, string, time.Time:
-				x = xx
-			case *int:
-				x = *xx
-			case *float64:
-				x = *xx
-			case *string:
-				x = *xx
-			case *time.Time:
-				x = *xx
+		// appending to the batch doesn't check ctx
+		if e := ctx.Err(); e != nil {
+			return abort(e)
+		}
+
+		vals := rowValues(row)
+		if _, e := stmt.ExecContext(ctx, vals...); e != nil {
+			return abort(e)
+		}
+
+		rows++
+		batchRows++
+		d.report(tableName, rows, false)
+
+		if size += rowSize(vals); bsize > 0 && size >= bsize {
+			e := tx.Commit()
+			done(batchRows, e)
+			if e != nil {
+				return e
 			}
 
-			buffer = append(append(buffer, []byte(d.ToString(x))...), bSep)
+			tx, size, batchRows = nil, 0, 0
+		}
+	}
+
+	if tx != nil {
+		e := tx.Commit()
+		done(batchRows, e)
+		if e != nil {
+			return e
 		}
+	}
+
+	d.report(tableName, rows, true)
 
-		buffer[len(buffer)-1] = bClose
+	return nil
+}
 
-		if bsize > 0 && len(buffer) >= bsize {
-			if e := d.InsertValues(tableName, buffer); e != nil {
+// copySave uses COPY FROM STDIN to load the rows into a Postgres table.
+func (d *Dialect) copySave(ctx context.Context, tableName string, fieldNames []string, df HasIter) error {
+	var (
+		conn *pgx.Conn
+		e    error
+	)
+	if conn, e = stdlib.AcquireConn(d.db); e != nil {
+		return e
+	}
+	defer func() { _ = stdlib.ReleaseConn(d.db, conn) }()
+
+	// unquoted names are folded to lower case by Postgres, but CopyFrom quotes them
+	schema, name := d.tableParts(tableName)
+	table := pgx.Identifier{name}
+	if schema != "" {
+		table = pgx.Identifier{schema, name}
+	}
+
+	next, stop := iter.Pull2(df.AllRows())
+	defer stop()
+
+	src := &copySource{ctx: ctx, next: next, d: d, tableName: tableName}
+
+	_, done := d.observe(ctx, "Save", fmt.Sprintf("COPY %s (%s) FROM STDIN", tableName, strings.Join(fieldNames, ",")))
+	if _, e1 := conn.CopyFrom(table, fieldNames, src); e1 != nil {
+		done(src.rows, e1)
+		return e1
+	}
+
+	done(src.rows, nil)
+
+	d.report(tableName, src.rows, true)
+
+	return nil
+}
+
+// copySource feeds the rows of a HasIter to CopyFrom.  CopyFrom doesn't take a context, so the source
+// stops if ctx is done.
+type copySource struct {
+	ctx  context.Context
+	next func() (int, []any, bool)
+	row  []any
+	rows int
+
+	d         *Dialect
+	tableName string
+}
+
+func (cs *copySource) Next() bool {
+	if cs.ctx.Err() != nil {
+		return false
+	}
+
+	var ok bool
+	if _, cs.row, ok = cs.next(); ok {
+		cs.rows++
+		cs.d.report(cs.tableName, cs.rows, false)
+	}
+
+	return ok
+}
+
+func (cs *copySource) Values() ([]any, error) {
+	return rowValues(cs.row), nil
+}
+
+func (cs *copySource) Err() error {
+	return cs.ctx.Err()
+}
+
+// insertSave inserts the rows into a Postgres table using multi-row INSERTs with placeholders.
+func (d *Dialect) insertSave(ctx context.Context, tableName string, df HasIter) error {
+	// Postgres allows at most 65535 parameters in a statement
+	const maxParams = 65535
+
+	var batch [][]any
+	bsize := d.bufSize * 1024 * 1024
+	size, rows := 0, 0
+
+	for _, row := range df.AllRows() {
+		if e := ctx.Err(); e != nil {
+			return e
+		}
+
+		vals := rowValues(row)
+		batch = append(batch, vals)
+		rows++
+		d.report(tableName, rows, false)
+
+		if size += rowSize(vals); (bsize > 0 && size >= bsize) || (len(batch)+1)*len(row) > maxParams {
+			if e := d.insertBatch(ctx, tableName, batch); e != nil {
 				return e
 			}
-			fmt.Println(string(buffer))
-			buffer = nil
+
+			batch, size = nil, 0
 		}
 	}
 
-	if buffer != nil {
-		if e := d.InsertValues(tableName, buffer); e != nil {
+	if batch != nil {
+		if e := d.insertBatch(ctx, tableName, batch); e != nil {
 			return e
 		}
 	}
 
+	d.report(tableName, rows, true)
+
 	return nil
 }
 
+// insertBatch inserts rows into tableName with a single statement with placeholders.
+func (d *Dialect) insertBatch(ctx context.Context, tableName string, rows [][]any) error {
+	var (
+		values []string
+		args   []any
+	)
+	for _, row := range rows {
+		var ph []string
+		for _, val := range row {
+			args = append(args, val)
+			ph = append(ph, fmt.Sprintf("$%d", len(args)))
+		}
+
+		values = append(values, "("+strings.Join(ph, ",")+")")
+	}
+
+	_, e := d.exec(ctx, "Save", fmt.Sprintf("INSERT INTO %s VALUES %s", tableName, strings.Join(values, ",")), args...)
+
+	return e
+}
+
+// rowValues dereferences the values in a row from AllRows.
+func rowValues(row []any) []any {
+	vals := make([]any, len(row))
+	for ind := range len(row) {
+		switch x := row[ind].(type) {
+		case *int:
+			vals[ind] = *x
+		case *float64:
+			vals[ind] = *x
+		case *string:
+			vals[ind] = *x
+		case *time.Time:
+			vals[ind] = *x
+		default:
+			vals[ind] = x
+		}
+	}
+
+	return vals
+}
+
+// rowSize approximates the number of bytes sent for a row.
+func rowSize(vals []any) int {
+	size := 0
+	for _, val := range vals {
+		size += 8
+		if s, ok := val.(string); ok {
+			size += len(s)
+		}
+	}
+
+	return size
+}
+
+// report calls the progress function every progressRows rows and when the save is done.
+func (d *Dialect) report(tableName string, rows int, done bool) {
+	if d.progress == nil || (!done && rows%d.progressRows != 0) {
+		return
+	}
+
+	d.progress(tableName, rows, done)
+}
+
+// observe starts the log entry and span for op running qry. The returned function ends them and is
+// called with the rows and error of the statement.
+func (d *Dialect) observe(ctx context.Context, op, qry string) (context.Context, func(rows int, e error)) {
+	if d.logger == nil && d.tracer == nil {
+		return ctx, func(int, error) {}
+	}
+
+	var span trace.Span
+	if d.tracer != nil {
+		ctx, span = d.tracer.Start(ctx, "df."+op, trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				attribute.String("db.system", d.DialectName()),
+				attribute.String("db.operation", op),
+				attribute.String("db.statement", qry)))
+	}
+
+	start := time.Now()
+	done := func(rows int, e error) {
+		if span != nil {
+			span.SetAttributes(attribute.Int("db.rows", rows))
+			if e != nil {
+				span.RecordError(e)
+				span.SetStatus(codes.Error, e.Error())
+			}
+
+			span.End()
+		}
+
+		if d.logger != nil {
+			d.logger(&QueryEvent{Op: op, SQL: qry, Duration: time.Since(start), Rows: rows, Err: e})
+		}
+	}
+
+	return ctx, done
+}
+
+// fieldInfo returns the names and types of the fields of qry.  If fieldTypes isn't nil, the names are numbered.
+func (d *Dialect) fieldInfo(ctx context.Context, qry string, fieldTypes []DataTypes) ([]string, []DataTypes, error) {
+	if fieldTypes == nil {
+		fieldNames, fieldTypes, _, _, e := d.types(ctx, qry)
+		return fieldNames, fieldTypes, e
+	}
+
+	var fieldNames []string
+	for ind := range len(fieldTypes) {
+		fieldNames = append(fieldNames, fmt.Sprintf("%d", ind))
+	}
+
+	return fieldNames, fieldTypes, nil
+}
+
+// stream runs qry as op and calls fn on each row, converted to fieldTypes, until fn returns false.
+func (d *Dialect) stream(ctx context.Context, op, qry string, fieldNames []string, fieldTypes []DataTypes, fn func(row []any) bool) error {
+	if d.fetchSize > 0 && d.DialectName() == pg {
+		return d.cursorStream(ctx, op, qry, fieldNames, fieldTypes, fn)
+	}
+
+	if d.fetchSize > 0 && d.DialectName() == ch {
+		ctx = clickhouse.Context(ctx, clickhouse.WithSettings(clickhouse.Settings{"max_block_size": d.fetchSize}))
+	}
+
+	ctx, done := d.observe(ctx, op, qry)
+
+	var (
+		rows *sql.Rows
+		e    error
+	)
+	if rows, e = d.db.QueryContext(ctx, qry); e != nil {
+		done(-1, e)
+		return e
+	}
+	defer func() { _ = rows.Close() }()
+
+	n, _, e := d.scanRows(rows, fieldNames, fieldTypes, fn)
+	done(n, e)
+
+	return e
+}
+
+// cursorStream streams qry through a Postgres server-side cursor, fetching fetchSize rows at a time.
+func (d *Dialect) cursorStream(ctx context.Context, op, qry string, fieldNames []string, fieldTypes []DataTypes, fn func(row []any) bool) error {
+	ctx, done := d.observe(ctx, op, qry)
+
+	var (
+		tx *sql.Tx
+		e  error
+	)
+	if tx, e = d.db.BeginTx(ctx, nil); e != nil {
+		done(-1, e)
+		return e
+	}
+	// the cursor only reads, so there's nothing to commit
+	defer func() { _ = tx.Rollback() }()
+
+	cursor := "df_" + RandomLetters(8)
+	if _, e = tx.ExecContext(ctx, fmt.Sprintf("DECLARE %s NO SCROLL CURSOR FOR %s", cursor, qry)); e != nil {
+		done(-1, e)
+		return e
+	}
+
+	fetch, total := fmt.Sprintf("FETCH %d FROM %s", d.fetchSize, cursor), 0
+	for {
+		var rows *sql.Rows
+		if rows, e = tx.QueryContext(ctx, fetch); e != nil {
+			done(total, e)
+			return e
+		}
+
+		n, more, ex := d.scanRows(rows, fieldNames, fieldTypes, fn)
+		_ = rows.Close()
+		total += n
+
+		if ex != nil || !more || n < d.fetchSize {
+			done(total, ex)
+			return ex
+		}
+	}
+}
+
+// scanRows calls fn on each of rows, converted to fieldTypes.  It returns the number of rows read and false
+// if fn stopped the scan.
+func (d *Dialect) scanRows(rows *sql.Rows, fieldNames []string, fieldTypes []DataTypes, fn func(row []any) bool) (n int, more bool, err error) {
+	row2read := make([]any, len(fieldTypes))
+	for ind := range len(row2read) {
+		var x any
+		row2read[ind] = &x
+	}
+
+	for rows.Next() {
+		if e := rows.Scan(row2read...); e != nil {
+			return n, false, e
+		}
+
+		row := make([]any, len(fieldTypes))
+		for ind := range len(row) {
+			var e error
+			if row[ind], e = d.ConvertTo(*row2read[ind].(*any), fieldTypes[ind]); e != nil {
+				return n, false, fmt.Errorf("field %s, row %d: %w", fieldNames[ind], n, e)
+			}
+		}
+
+		n++
+		if !fn(row) {
+			return n, false, nil
+		}
+	}
+
+	// a cancelled context ends the loop early
+	return n, true, rows.Err()
+}
+
+// query runs qry as op and calls scan on each row it returns.
+func (d *Dialect) query(ctx context.Context, op, qry string, scan func(rows *sql.Rows) error) error {
+	ctx, done := d.observe(ctx, op, qry)
+
+	var (
+		rows *sql.Rows
+		e    error
+	)
+	if rows, e = d.db.QueryContext(ctx, qry); e != nil {
+		done(-1, e)
+		return e
+	}
+	defer func() { _ = rows.Close() }()
+
+	n := 0
+	for rows.Next() {
+		if e = scan(rows); e != nil {
+			done(n, e)
+			return e
+		}
+
+		n++
+	}
+
+	e = rows.Err()
+	done(n, e)
+
+	return e
+}
+
+// exec runs qry as op, returning the number of rows affected (-1 if not known).
+func (d *Dialect) exec(ctx context.Context, op, qry string, args ...any) (int, error) {
+	ctx, done := d.observe(ctx, op, qry)
+
+	rows := -1
+	res, e := d.db.ExecContext(ctx, qry, args...)
+	if e == nil {
+		if n, ex := res.RowsAffected(); ex == nil {
+			rows = int(n)
+		}
+	}
+
+	done(rows, e)
+
+	return rows, e
+}
+
 // Join creates an inner JOIN query.
 //
 //	leftSQL - SQL for left side of join
@@ -609,72 +1365,60 @@ func (d *Dialect) Join(leftSQL, rightSQL string, leftFields, rightFields, joinFi
 	return qry
 }
 
-// Load loads qry from a DB into a slice of *Vector.
+// Load is LoadContext with a background context.
+func (d *Dialect) Load(qry string) (memData []*Vector, fieldNames []string, fieldTypes []DataTypes, e error) {
+	return d.LoadContext(context.Background(), qry)
+}
+
+// LoadContext loads qry from a DB into a slice of *Vector.
 //
 //	memData    - returned data
 //	fieldNames - field names of columns
 //	fieldTypes - field types
-func (d *Dialect) Load(qry string) (memData []*Vector, fieldNames []string, fieldTypes []DataTypes, e error) {
-	var row2read []any
-	fieldNames, fieldTypes, row2read, e = d.Types(qry)
-	if e != nil {
+func (d *Dialect) LoadContext(ctx context.Context, qry string) (memData []*Vector, fieldNames []string, fieldTypes []DataTypes, e error) {
+	var dbTypes []string
+	if fieldNames, fieldTypes, dbTypes, _, e = d.types(ctx, qry); e != nil {
 		return nil, nil, nil, e
 	}
 
-	var (
-		n  int
-		e2 error
-	)
-	if n, e2 = d.RowCount(qry); e2 != nil {
-		return nil, nil, nil, e2
-	}
-
 	for ind := range len(fieldTypes) {
-		memData = append(memData, MakeVector(fieldTypes[ind], n))
-	}
+		var v *Vector
+		if v, e = MakeVectorE(fieldTypes[ind], 0); e != nil {
+			return nil, nil, nil, fmt.Errorf("field %s: %w", fieldNames[ind], e)
+		}
 
-	var (
-		rows *sql.Rows
-		e3   error
-	)
-	if rows, e3 = d.db.Query(qry); e3 != nil {
-		return nil, nil, nil, e3
+		memData = append(memData, v)
 	}
 
-	indx := 0
-	for rows.Next() {
-		if e4 := rows.Scan(row2read...); e4 != nil {
-			return nil, nil, nil, e4
-		}
-
-		for ind := range len(memData) {
-			var z = *row2read[ind].(*any)
-			if z == nil {
-				switch memData[ind].dt {
-				case DTfloat:
-					z = d.defaultFloat
-				case DTint:
-					z = d.defaultInt
-				case DTstring:
-					z = d.defaultString
-				case DTdate:
-					z = d.defaultDate
-				}
+	var ePush error
+	add := func(row []any) bool {
+		for ind, val := range row {
+			if ePush = memData[ind].push(val); ePush != nil {
+				ePush = fmt.Errorf("field %s: %w", fieldNames[ind], ePush)
+				return false
 			}
-
-			d.assign(memData[ind], z, indx)
 		}
 
-		indx++
+		return true
+	}
+
+	if e = d.stream(ctx, "Load", qry, fieldNames, fieldTypes, add); e != nil {
+		return nil, nil, nil, e
+	}
+
+	if ePush != nil {
+		return nil, nil, nil, ePush
 	}
 
-	// change any dates to midnight UTC o.w. comparisons may not work
+	// change any dates to midnight UTC o.w. comparisons may not work.  Timestamps keep their time.
 	for c := range len(memData) {
 		if fieldTypes[c] != DTdate {
 			continue
 		}
 
-		utc(memData[c])
+		if ex := utc(memData[c], isTimestamp(dbTypes[c])); ex != nil {
+			return nil, nil, nil, ex
+		}
 	}
 
 	return memData, fieldNames, fieldTypes, nil
@@ -698,38 +1442,84 @@ func (d *Dialect) Quote() string {
 	return "'"
 }
 
+// RegisterFunction adds spec to the functions available to the parser.  If a function of the
+// same name exists, it is replaced.  DFs created before the call do not see the function.
+func (d *Dialect) RegisterFunction(spec *FnSpec) error {
+	if spec == nil {
+		return fmt.Errorf("nil spec to RegisterFunction")
+	}
+
+	if e := spec.check(); e != nil {
+		return e
+	}
+
+	if d.functions == nil {
+		d.functions = make(Fmap)
+	}
+
+	d.functions[spec.Name] = spec
+
+	return nil
+}
+
+// RowCount is RowCountContext with a background context.
 func (d *Dialect) RowCount(qry string) (int, error) {
+	return d.RowCountContext(context.Background(), qry)
+}
+
+// RowCountContext returns the number of rows qry returns.
+func (d *Dialect) RowCountContext(ctx context.Context, qry string) (int, error) {
 	const skeleton = "WITH %s AS (%s) SELECT count(*) AS n FROM %s"
 	var n int
 
 	sig := d.WithName()
 	q := fmt.Sprintf(skeleton, sig, qry, sig)
-	row := d.db.QueryRow(q)
+
+	ctx, done := d.observe(ctx, "RowCount", q)
+	row := d.db.QueryRowContext(ctx, q)
 	if e := row.Scan(&n); e != nil {
+		done(-1, e)
 		return 0, e
 	}
 
+	done(n, nil)
+
 	return n, nil
 }
 
-// Rows returns a row reader for qry.
+// Rows is RowsContext with a background context.
+func (d *Dialect) Rows(qry string) (rows *sql.Rows, row2Read []any, fieldNames []string, e error) {
+	return d.RowsContext(context.Background(), qry)
+}
+
+// RowsContext returns a row reader for qry.
 //
 //	rows       - row reader
 //	row2Read   - a slice with the appropriate types to read the rows.
 //	fieldNames - names of the columns
-func (d *Dialect) Rows(qry string) (rows *sql.Rows, row2Read []any, fieldNames []string, e error) {
-	if fieldNames, _, row2Read, e = d.Types(qry); e != nil {
+func (d *Dialect) RowsContext(ctx context.Context, qry string) (rows *sql.Rows, row2Read []any, fieldNames []string, e error) {
+	if fieldNames, _, row2Read, e = d.TypesContext(ctx, qry); e != nil {
 		return nil, nil, nil, e
 	}
 
-	if rows, e = d.db.Query(qry); e != nil {
+	// the rows are read by the caller, so this only times running the query
+	qctx, done := d.observe(ctx, "Rows", qry)
+	rows, e = d.db.QueryContext(qctx, qry)
+	done(-1, e)
+
+	if e != nil {
 		return nil, nil, nil, e
 	}
 
 	return rows, row2Read, fieldNames, nil
 }
 
-// Save saves an Iter object to a database.
+// Save is SaveContext with a background context.
+func (d *Dialect) Save(tableName, orderBy string, overwrite, temp bool, toSave HasIter, options ...string) error {
+	return d.SaveContext(context.Background(), tableName, orderBy, overwrite, temp, toSave, options...)
+}
+
+// SaveContext saves an Iter object to a database.
 //
 //	tableName - name of table to create.
 //	orderBy   - comma-separated list of fields to use as key (order).
@@ -737,52 +1527,76 @@ func (d *Dialect) Rows(qry string) (rows *sql.Rows, row2Read []any, fieldNames [
 //	temp      - if true, create a temp table.
 //	toSave    - data to save.
 //	options   - options for CREATE.
-func (d *Dialect) Save(tableName, orderBy string, overwrite, temp bool, toSave HasIter, options ...string) error {
+func (d *Dialect) SaveContext(ctx context.Context, tableName, orderBy string, overwrite, temp bool, toSave HasIter, options ...string) error {
 	var (
 		fieldNames []string
 		fieldTypes []DataTypes
+		e0         error
 	)
-	switch x := toSave.(type) {
-	case DF:
-		fieldNames = x.ColumnNames()
-		fieldTypes, _ = x.ColumnTypes()
-	case *Vector:
-		fieldNames = []string{"col"}
-		fieldTypes = []DataTypes{x.VectorType()}
-		orderBy = "col"
-	case Column:
-		fieldNames = []string{x.Name()}
-		fieldTypes = []DataTypes{x.DataType()}
-		orderBy = x.Name()
-	default:
-		return fmt.Errorf("cannot save type to sql table")
+	if fieldNames, fieldTypes, e0 = saveFields(toSave); e0 != nil {
+		return e0
+	}
+
+	// a Vector or Column is ordered by itself
+	if _, isDF := toSave.(DF); !isDF {
+		orderBy = fieldNames[0]
+	}
+
+	var (
+		exist bool
+		e1    error
+	)
+	if exist, e1 = d.tableExists(ctx, tableName); e1 != nil {
+		return e1
 	}
 
-	if d.Exists(tableName) {
+	if exist {
 		if !overwrite {
 			return fmt.Errorf("table %s exists", tableName)
 		}
 
-		if e := d.DropTable(tableName); e != nil {
+		if e := d.DropTableContext(ctx, tableName); e != nil {
 			return e
 		}
 	}
 
-	if e := d.Create(tableName, orderBy, fieldNames, fieldTypes, true, temp, options...); e != nil {
+	if e := d.CreateContext(ctx, tableName, orderBy, fieldNames, fieldTypes, true, temp, options...); e != nil {
 		return e
 	}
 
 	// If there's a MakeQuery method, use that
 	if df, ok := toSave.(HasMQdlct); ok {
-		qry := df.MakeQuery()
+		var (
+			qry string
+			e   error
+		)
+		if qry, e = df.MakeQueryE(); e != nil {
+			return e
+		}
+
 		for ind, cn := range fieldNames {
 			fieldNames[ind] = d.ToName(cn)
 		}
 
-		return d.Insert(tableName, qry, strings.Join(fieldNames, ","))
+		return d.InsertContext(ctx, tableName, qry, strings.Join(fieldNames, ","))
 	}
 
-	return d.IterSave(tableName, toSave)
+	return d.IterSaveContext(ctx, tableName, toSave)
+}
+
+// saveFields returns the names and types of the columns of toSave.
+func saveFields(toSave HasIter) (fieldNames []string, fieldTypes []DataTypes, err error) {
+	switch x := toSave.(type) {
+	case DF:
+		fieldTypes, _ = x.ColumnTypes()
+		return x.ColumnNames(), fieldTypes, nil
+	case *Vector:
+		return []string{"col"}, []DataTypes{x.VectorType()}, nil
+	case Column:
+		return []string{x.Name()}, []DataTypes{x.DataType()}, nil
+	default:
+		return nil, nil, fmt.Errorf("cannot save type to sql table")
+	}
 }
 
 // Seq returns a query that creates a table with column "seq" whose int values run from 0 to n-1.
@@ -794,6 +1608,63 @@ func (d *Dialect) Seq(n int) string {
 	return strings.ReplaceAll(d.seq, "?Upper", fmt.Sprintf("%d", n))
 }
 
+// Stream is StreamContext with a background context.
+func (d *Dialect) Stream(qry string, fieldTypes []DataTypes, fn func(row []any) bool) error {
+	return d.StreamContext(context.Background(), qry, fieldTypes, fn)
+}
+
+// StreamContext calls fn on each row of qry without loading the result into memory.  The values of a row are
+// converted to fieldTypes.  If fieldTypes is nil, the types are found from the query.  Streaming stops if fn
+// returns false.
+func (d *Dialect) StreamContext(ctx context.Context, qry string, fieldTypes []DataTypes, fn func(row []any) bool) error {
+	var (
+		fieldNames []string
+		e          error
+	)
+	if fieldNames, fieldTypes, e = d.fieldInfo(ctx, qry, fieldTypes); e != nil {
+		return e
+	}
+
+	return d.stream(ctx, "Stream", qry, fieldNames, fieldTypes, fn)
+}
+
+// Tables is TablesContext with a background context.
+func (d *Dialect) Tables(schema string) ([]string, error) {
+	return d.TablesContext(context.Background(), schema)
+}
+
+// TablesContext returns the names of the tables in schema (the database, for ClickHouse).  If schema is empty,
+// the current schema is used.  Temporary tables are not included.
+func (d *Dialect) TablesContext(ctx context.Context, schema string) ([]string, error) {
+	qry := strings.ReplaceAll(d.tables, "?Schema", d.schemaSQL(schema))
+
+	var tables []string
+	scan := func(rows *sql.Rows) error {
+		var table string
+		if e := rows.Scan(&table); e != nil {
+			return e
+		}
+
+		tables = append(tables, table)
+
+		return nil
+	}
+
+	if e := d.query(ctx, "Tables", qry, scan); e != nil {
+		return nil, e
+	}
+
+	return tables, nil
+}
+
+// TableStatsQuery returns a query that summarizes the tables in schema (the database, for ClickHouse) using the
+// database's catalog rather than scanning the tables.  If schema is empty, the current schema is used.  The query
+// has a row for each table with the fields name, rows, columns and bytes.  rows is the catalog's estimate: for
+// Postgres, it is as of the last ANALYZE of the table.  See mem.TableStats.
+func (d *Dialect) TableStatsQuery(schema string) string {
+	return strings.ReplaceAll(d.stats, "?Schema", d.schemaSQL(schema))
+}
+
 // ToName converts the raw field name to what's need for a interaction with the database.
 // Specifically, Postgres requires quotes around field names that have uppercase letters
 func (d *Dialect) ToName(fieldName string) string {
@@ -806,30 +1677,70 @@ func (d *Dialect) ToName(fieldName string) string {
 	return fieldName
 }
 
-// ToString returns a string version of val that can be placed into SQL
+// ToString returns a string version of val that can be placed into SQL. It panics if val's type is not supported.
 func (d *Dialect) ToString(val any) string {
+	var (
+		x string
+		e error
+	)
+	if x, e = d.ToStringE(val); e != nil {
+		panic(e)
+	}
+
+	return x
+}
+
+// ToStringE returns a string version of val that can be placed into SQL.
+func (d *Dialect) ToStringE(val any) (string, error) {
 	var (
 		xv any
 		ok bool
 	)
 	if xv, ok = toString(val); !ok {
-		panic(fmt.Errorf("can't make string"))
+		return "", fmt.Errorf("cannot make SQL string from type %T", val)
 	}
 
 	x := xv.(string)
 	if WhatAmI(val) == DTdate || WhatAmI(val) == DTstring {
-		x = fmt.Sprintf("'%s'", x)
+		x = d.literal(x)
 	}
 
-	return x
+	return x, nil
+}
+
+// literal returns s as a SQL string literal with quotes and backslashes escaped.
+func (d *Dialect) literal(s string) string {
+	switch {
+	case d.DialectName() == ch:
+		// ClickHouse treats a backslash as an escape character within a string
+		s = strings.ReplaceAll(s, `\`, `\\`)
+		return "'" + strings.ReplaceAll(s, "'", `\'`) + "'"
+	case strings.Contains(s, `\`):
+		// escape string so the result doesn't depend on standard_conforming_strings
+		s = strings.ReplaceAll(s, `\`, `\\`)
+		return "E'" + strings.ReplaceAll(s, "'", "''") + "'"
+	default:
+		return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+	}
+}
+
+// Types is TypesContext with a background context.
+func (d *Dialect) Types(qry string) (fieldNames []string, fieldTypes []DataTypes, row2read []any, err error) {
+	return d.TypesContext(context.Background(), qry)
 }
 
-// Types returns info needed to read the data generated by qry.
+// TypesContext returns info needed to read the data generated by qry.
 //
 //	fieldNames - names of columns qry returns.
 //	fieldTypes - column types returned by qry.
 //	row2Read   - correctly typed row to read for Scan.
-func (d *Dialect) Types(qry string) (fieldNames []string, fieldTypes []DataTypes, row2read []any, err error) {
+func (d *Dialect) TypesContext(ctx context.Context, qry string) (fieldNames []string, fieldTypes []DataTypes, row2read []any, err error) {
+	fieldNames, fieldTypes, _, row2read, err = d.types(ctx, qry)
+	return fieldNames, fieldTypes, row2read, err
+}
+
+// types is TypesContext that also returns the DB types of the fields.
+func (d *Dialect) types(ctx context.Context, qry string) (fieldNames []string, fieldTypes []DataTypes, dbTypes []string, row2read []any, err error) {
 	const skeleton = "WITH %s AS (%s) SELECT * FROM %s LIMIT 1"
 
 	sig := d.WithName()
@@ -839,8 +1750,12 @@ func (d *Dialect) Types(qry string) (fieldNames []string, fieldTypes []DataTypes
 		r  *sql.Rows
 		e0 error
 	)
-	if r, e0 = d.db.Query(q); e0 != nil {
-		return nil, nil, nil, e0
+	qctx, done := d.observe(ctx, "Types", q)
+	r, e0 = d.db.QueryContext(qctx, q)
+	done(-1, e0)
+
+	if e0 != nil {
+		return nil, nil, nil, nil, e0
 	}
 	defer func() {
 		{
@@ -853,7 +1768,7 @@ func (d *Dialect) Types(qry string) (fieldNames []string, fieldTypes []DataTypes
 		e1 error
 	)
 	if ct, e1 = r.ColumnTypes(); e1 != nil {
-		return nil, nil, nil, e1
+		return nil, nil, nil, nil, e1
 	}
 
 	var ry []any
@@ -863,38 +1778,28 @@ func (d *Dialect) Types(qry string) (fieldNames []string, fieldTypes []DataTypes
 	}
 	for r.Next() {
 		if e1 := r.Scan(ry...); e1 != nil {
-			return nil, nil, nil, e1
+			return nil, nil, nil, nil, e1
 		}
 	}
 
 	var (
-		names []string
-		dts   []DataTypes
+		names   []string
+		dts     []DataTypes
+		dbNames []string
 	)
 
 	for ind := range len(ry) {
-		names = append(names, ct[ind].Name())
-		var dt DataTypes
+		name, dbType := ct[ind].Name(), ct[ind].DatabaseTypeName()
 
-		var z = *ry[ind].(*any)
-		switch z.(type) {
-		case int, int8, int16, int32, int64, *int, *int16, *int32, *int64,
-			uint, uint8, uint16, uint32, uint64, *uint, *uint8, *uint16, *uint32, *uint64:
-			dt = DTint
-		case float32, float64, *float32, *float64:
-			dt = DTfloat
-		case string, *string:
-			dt = DTstring
-		case time.Time, *time.Time:
-			dt = DTdate
-		default:
-			panic(fmt.Errorf("OH NO bad datatype"))
+		var dt DataTypes
+		if dt = d.fieldType(name, dbType, *ry[ind].(*any)); dt == DTunknown {
+			return nil, nil, nil, nil, fmt.Errorf("unsupported type %s for field %s", dbType, name)
 		}
 
-		dts = append(dts, dt)
+		names, dts, dbNames = append(names, name), append(dts, dt), append(dbNames, dbType)
 	}
 
-	return names, dts, ry, nil
+	return names, dts, dbNames, ry, nil
 }
 
 // Union returns a union query between two tables (queries).
@@ -931,36 +1836,134 @@ func (d *Dialect) dbtype(dt DataTypes) (string, error) {
 	return d.dbTypes[pos], nil
 }
 
-// assign assigns the indx vector of v to be val
-func (d *Dialect) assign(v *Vector, val any, indx int) {
-	var e error
-	switch v.VectorType() {
-	case DTfloat:
-		e = v.SetFloat(d.Convert(val).(float64), indx)
-	case DTint:
-		e = v.SetInt(d.Convert(val).(int), indx)
-	case DTstring:
-		e = v.SetString(d.Convert(val).(string), indx)
-	case DTdate:
-		e = v.SetDate(d.Convert(val).(time.Time), indx)
+// isNil returns true if x is nil or a nil pointer.
+func isNil(x any) bool {
+	if x == nil {
+		return true
 	}
 
-	if e != nil {
-		panic(e)
+	rv := reflect.ValueOf(x)
+
+	return rv.Kind() == reflect.Pointer && rv.IsNil()
+}
+
+// tableParts splits tableName into its schema (database) and table.  The schema is empty if tableName doesn't
+// have one.  Postgres folds unquoted names to lower case.
+func (d *Dialect) tableParts(tableName string) (schema, table string) {
+	parts := strings.Split(tableName, ".")
+	for ind, part := range parts {
+		switch {
+		case strings.HasPrefix(part, `"`):
+			parts[ind] = strings.Trim(part, `"`)
+		case d.DialectName() == pg:
+			parts[ind] = strings.ToLower(part)
+		}
+	}
+
+	if len(parts) == 1 {
+		return "", parts[0]
 	}
+
+	return strings.Join(parts[:len(parts)-1], "."), parts[len(parts)-1]
 }
 
-// utc changes the entries of date slices to be midnight UTC
-func utc(v *Vector) {
+// schemaSQL returns the SQL for schema, which is the current schema (database) if schema is empty.
+func (d *Dialect) schemaSQL(schema string) string {
+	if schema != "" {
+		return d.literal(schema)
+	}
+
+	if d.DialectName() == ch {
+		return "currentDatabase()"
+	}
+
+	return "current_schema()"
+}
+
+// dtFromDB returns the DataTypes corresponding to the database type dbType.
+func dtFromDB(dbType string) DataTypes {
+	t := strings.ToLower(dbType)
+
+	// ClickHouse wrappers don't change the type.  They nest in either order, e.g. LowCardinality(Nullable(String)).
+	for wrapped := true; wrapped; {
+		wrapped = false
+		for _, wrap := range []string{"nullable(", "lowcardinality("} {
+			if strings.HasPrefix(t, wrap) {
+				t, wrapped = strings.TrimSuffix(strings.TrimPrefix(t, wrap), ")"), true
+			}
+		}
+	}
+
+	switch {
+	// arrays, maps, tuples and the like are loaded as strings
+	case strings.HasPrefix(t, "array"), strings.HasPrefix(t, "_"), strings.HasPrefix(t, "map("),
+		strings.HasPrefix(t, "tuple("), strings.HasPrefix(t, "json"), strings.HasPrefix(t, "interval"):
+		return DTstring
+	case strings.HasPrefix(t, "int"), strings.HasPrefix(t, "uint"), strings.HasPrefix(t, "bigint"),
+		strings.HasPrefix(t, "smallint"), strings.HasPrefix(t, "bool"):
+		return DTint
+	case strings.HasPrefix(t, "float"), strings.HasPrefix(t, "double"), strings.HasPrefix(t, "real"),
+		strings.HasPrefix(t, "numeric"), strings.HasPrefix(t, "decimal"):
+		return DTfloat
+	case strings.HasPrefix(t, "date"), strings.HasPrefix(t, "timestamp"):
+		return DTdate
+	case strings.HasPrefix(t, "string"), strings.HasPrefix(t, "fixedstring"), strings.HasPrefix(t, "text"),
+		strings.HasPrefix(t, "char"), strings.HasPrefix(t, "varchar"), strings.HasPrefix(t, "character"),
+		strings.HasPrefix(t, "bpchar"), strings.HasPrefix(t, "uuid"), strings.HasPrefix(t, "enum"),
+		strings.HasPrefix(t, "ipv"):
+		return DTstring
+	default:
+		return DTunknown
+	}
+}
+
+// fieldType returns the DataTypes to use for fieldName.  In order, it uses
+//   - the type set by DialectColumnType
+//   - the type corresponding to dbType
+//   - the type of val, a value of the field
+//   - the type set by DialectFallback.
+func (d *Dialect) fieldType(fieldName, dbType string, val any) DataTypes {
+	if dt, ok := d.columnTypes[fieldName]; ok {
+		return dt
+	}
+
+	if dt := dtFromDB(dbType); dt != DTunknown {
+		return dt
+	}
+
+	if x, e := d.ConvertE(val); e == nil && x != nil {
+		if dt := WhatAmI(x); dt != DTunknown {
+			return dt
+		}
+	}
+
+	return d.fallback
+}
+
+// isTimestamp returns true if dbType holds a time of day as well as a date.
+func isTimestamp(dbType string) bool {
+	t := strings.ToLower(dbType)
+	return strings.Contains(t, "datetime") || strings.Contains(t, "timestamp")
+}
+
+// utc changes the entries of date slices to be midnight UTC.  If keepTime, only the time zone is changed.
+func utc(v *Vector, keepTime bool) error {
 	var (
 		col []time.Time
 		e   error
 	)
 	if col, e = v.AsDate(); e != nil {
-		panic(e)
+		return e
 	}
 
 	for rx := 0; rx < v.Len(); rx++ {
+		if keepTime {
+			col[rx] = col[rx].UTC()
+			continue
+		}
+
 		col[rx] = time.Date(col[rx].Year(), col[rx].Month(), col[rx].Day(), 0, 0, 0, 0, time.UTC)
 	}
+
+	return nil
 }
@@ -2,15 +2,38 @@ package df
 
 import (
 	"bufio"
+	"bytes"
+	"cmp"
 	"fmt"
 	"io"
 	"math"
 	"os"
+	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
+
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression methods for files.
+const (
+	CompressNone = ""
+	CompressGzip = "gzip"
+	CompressZstd = "zstd"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
 )
 
+// readerPeek is the # of records examined to determine data types if the input can't be re-read and FilePeek is 0.
+const readerPeek = 10000
+
 // Files manages interactions with files.
 type Files struct {
 	eol         byte
@@ -19,9 +42,14 @@ type Files struct {
 	dateFormat  string // For writing files. All formats in DateFormats will be tried when reading.
 	floatFormat string
 
-	header bool // file has header
-	peek   int  // # of records to look at to determine data types
-	strict bool // enforce field values must be strictly interpretable as the field type. If true, bad data throws an error, o.w. default value is used.
+	header      bool   // file has header
+	crlf        bool   // write CRLF line endings
+	compression string // compression for writing. If empty, Save uses the file extension. Reading detects it.
+	encoding    string // encoding of the file. If empty, UTF-8 unless sniffed.
+	sniff       bool   // infer the encoding, separator and header
+	reopened    bool   // Open is reading the file again after detect, so the header is known
+	peek        int    // # of records to look at to determine data types
+	strict      bool   // enforce field values must be strictly interpretable as the field type. If true, bad data throws an error, o.w. default value is used.
 
 	defaultInt    int       // default int value when bad data encountered.
 	defaultFloat  float64   // default float value when bad data encountered.
@@ -35,8 +63,58 @@ type Files struct {
 
 	lineWidth int // required if this is a flat file
 
-	file *os.File
-	rdr  *bufio.Reader
+	columns  []string // columns to load. If nil, all are loaded.
+	keep     []int    // indices of the columns to load
+	skipRows int      // # of data rows to skip
+	maxRows  int      // max # of rows to load. 0 means no limit.
+	filter   string   // condition rows must satisfy to be loaded
+	skipped  int      // # of rows skipped so far
+	loaded   int      // # of rows loaded so far
+
+	formats      map[string]*colFormat // per-field formats, by field name
+	colFmts      []colFormat           // per-field formats, by field position
+	na           []string              // values that are treated as missing
+	thousandsSep byte                  // thousands separator in numbers
+	decimalComma bool                  // numbers use a comma as the decimal point
+
+	report    bool       // record values that fail to convert
+	maxErrors int        // max # of bad values before loading fails. 0 means no limit.
+	rejects   string     // file to write bad values to
+	rejectsF  *os.File   // rejects file, created when the first bad value is found
+	badValues []BadValue // bad values found so far
+	lines     int        // # of lines read so far
+	recLine   int        // line on which the last record read starts
+
+	workers   int // # of goroutines parsing a separated file
+	chunkSize int // approximate # of bytes in each chunk parsed by a worker
+
+	file   *os.File
+	unzip  io.Closer // decompressor, if the input is compressed
+	rdr    *bufio.Reader
+	wtr    *bufio.Writer
+	peeked []record // records read by detect, returned before reading further
+}
+
+// colFormat holds formats for a field that override the file-wide formats.
+type colFormat struct {
+	date  string // date layout, which need not be in DateFormats
+	float string // float format for writing
+}
+
+// BadValue describes a value in a file that failed to convert to its field's type.
+type BadValue struct {
+	File   string    // file name, empty if read by OpenReader
+	Line   int       // line of the file on which the record starts, counting from 1
+	Column string    // field name
+	Raw    string    // value in the file
+	Type   DataTypes // type it failed to convert to
+}
+
+// record is a record split into fields, with flags for which were enclosed in string delimiters.
+type record struct {
+	vals   []string
+	quoted []bool
+	line   int // line on which the record starts
 }
 
 // NewFiles creates a *Files struct for reading/writing files.
@@ -48,6 +126,9 @@ func NewFiles(opts ...FileOpt) (*Files, error) {
 		dateFormat:    "20060102",
 		floatFormat:   "%.2f",
 		header:        true,
+		formats:       make(map[string]*colFormat),
+		workers:       1,
+		chunkSize:     1 << 22,
 		strict:        false,
 		defaultInt:    math.MaxInt,
 		defaultFloat:  math.MaxFloat64,
@@ -131,6 +212,88 @@ func FileDateFormat(format string) FileOpt {
 	}
 }
 
+// FileChunkSize sets the approximate # of bytes in each chunk of a separated file parsed by a worker. Default is 4MB.
+func FileChunkSize(bytes int) FileOpt {
+	return func(f *Files) error {
+		if bytes <= 0 {
+			return fmt.Errorf("chunk size must be positive")
+		}
+
+		f.chunkSize = bytes
+
+		return nil
+	}
+}
+
+// FileColumnDateFormat sets the date layout for field, for both reading and writing.  The layout is any Go time
+// layout, not only those in DateFormats.
+func FileColumnDateFormat(field, layout string) FileOpt {
+	return func(f *Files) error {
+		ref := time.Date(1999, 12, 31, 23, 58, 59, 0, time.UTC)
+		if layout == "" || ref.Format(layout) == layout {
+			return fmt.Errorf("invalid date layout for field %s: %s", field, layout)
+		}
+
+		if _, e := time.Parse(layout, ref.Format(layout)); e != nil {
+			return fmt.Errorf("invalid date layout for field %s: %s", field, layout)
+		}
+
+		f.format(field).date = layout
+
+		return nil
+	}
+}
+
+// FileColumnFloatFormat sets the format for writing field, for example %.4f.
+func FileColumnFloatFormat(field, format string) FileOpt {
+	return func(f *Files) error {
+		if e := checkFloatFormat(format); e != nil {
+			return e
+		}
+
+		f.format(field).float = format
+
+		return nil
+	}
+}
+
+// FileColumns sets the columns to load. By default, all columns are loaded.
+func FileColumns(names ...string) FileOpt {
+	return func(f *Files) error {
+		if len(names) == 0 {
+			return fmt.Errorf("no columns in FileColumns")
+		}
+
+		f.columns = names
+
+		return nil
+	}
+}
+
+// FileCompression sets the compression used when writing: CompressNone, CompressGzip or CompressZstd.
+// If not set, Save compresses files ending in .gz or .zst. Compressed input is always detected when reading.
+func FileCompression(method string) FileOpt {
+	return func(f *Files) error {
+		if method != CompressNone && method != CompressGzip && method != CompressZstd {
+			return fmt.Errorf("unsupported compression: %s", method)
+		}
+
+		f.compression = method
+
+		return nil
+	}
+}
+
+// FileCRLF sets whether Save ends lines with CRLF, as RFC 4180 specifies, rather than EOL. Default is false.
+// CRLF line endings are always accepted when reading.
+func FileCRLF(crlf bool) FileOpt {
+	return func(f *Files) error {
+		f.crlf = crlf
+
+		return nil
+	}
+}
+
 // FileEOL sets the end-of-line character.  The default is \n.
 func FileEOL(eol byte) FileOpt {
 	return func(f *Files) error {
@@ -182,11 +345,31 @@ func FileFieldWidths(fieldWidths []int) FileOpt {
 	}
 }
 
+// FileFilter sets a condition rows must satisfy to be loaded, for example "x > 0 && z != 'A'". The condition
+// is evaluated by mem.FileLoad on batches of rows as they are read and may refer only to the columns loaded.
+func FileFilter(condition string) FileOpt {
+	return func(f *Files) error {
+		f.filter = condition
+
+		return nil
+	}
+}
+
+// FileDecimalComma sets whether numbers use a comma as the decimal point, as in 1.234,5.  It applies when
+// reading and when writing floats.  Default is false.
+func FileDecimalComma(comma bool) FileOpt {
+	return func(f *Files) error {
+		f.decimalComma = comma
+
+		return nil
+	}
+}
+
 // FileFloatFormat sets the format for writing floats.  Default is %.2f.
 func FileFloatFormat(format string) FileOpt {
 	return func(f *Files) error {
-		if ok, _ := regexp.MatchString("%[0-9]?[0-9]?.[0-9]?[0-9]?f", format); !ok {
-			return fmt.Errorf("invalid float format: %s", format)
+		if e := checkFloatFormat(format); e != nil {
+			return e
 		}
 
 		f.floatFormat = format
@@ -204,8 +387,33 @@ func FileHeader(hasHeader bool) FileOpt {
 	}
 }
 
+// FileMaxRows sets the maximum # of rows to load. If FileFilter is set, this counts rows satisfying the condition.
+// The default of 0 loads all rows.  With mem.FilesLoad, the limit applies to each file.
+func FileMaxRows(n int) FileOpt {
+	return func(f *Files) error {
+		if n < 0 {
+			return fmt.Errorf("max rows cannot be negative")
+		}
+
+		f.maxRows = n
+
+		return nil
+	}
+}
+
+// FileNA sets the values that denote missing data, for example "NA", "" or ".".  These are replaced by the default
+// value for the field's type, even if strict is true, and are ignored when determining types.
+func FileNA(tokens ...string) FileOpt {
+	return func(f *Files) error {
+		f.na = tokens
+
+		return nil
+	}
+}
+
 // FilePeek sets the # of lines to examine to determine data types. Default value of 0
-// will examine the entire file.
+// will examine the entire file.  Open reads the lines examined again when loading, but OpenReader
+// can't, so it holds them in memory.  With the default, OpenReader examines the first 10,000 lines.
 func FilePeek(linesToPeek int) FileOpt {
 	return func(f *Files) error {
 		if linesToPeek < 0 {
@@ -218,6 +426,35 @@ func FilePeek(linesToPeek int) FileOpt {
 	}
 }
 
+// FileRejects sets a file to which values that fail to convert are written as CSV with fields file, line,
+// column, raw and type.  The file is created when the first bad value is found.  It implies FileReport with
+// no limit unless FileReport sets one.
+func FileRejects(fileName string) FileOpt {
+	return func(f *Files) error {
+		f.rejects = fileName
+		f.report = true
+
+		return nil
+	}
+}
+
+// FileReport sets Load to record each value that fails to convert, along with its line and field, rather than
+// either silently using the default or, if strict is true, failing.  The default value is still loaded.
+// Loading fails once more than maxErrors bad values are found.  If maxErrors is 0, there is no limit.
+// The bad values are available from BadValues.
+func FileReport(maxErrors int) FileOpt {
+	return func(f *Files) error {
+		if maxErrors < 0 {
+			return fmt.Errorf("max errors cannot be negative")
+		}
+
+		f.report = true
+		f.maxErrors = maxErrors
+
+		return nil
+	}
+}
+
 // FileSep sets the field separator.  Default is a comma.
 func FileSep(sep byte) FileOpt {
 	return func(f *Files) error {
@@ -227,6 +464,20 @@ func FileSep(sep byte) FileOpt {
 	}
 }
 
+// FileSkipRows sets the # of data rows to skip before loading. Skipped rows are still used to determine data types.
+// With mem.FilesLoad, the rows are skipped in each file.
+func FileSkipRows(n int) FileOpt {
+	return func(f *Files) error {
+		if n < 0 {
+			return fmt.Errorf("skip rows cannot be negative")
+		}
+
+		f.skipRows = n
+
+		return nil
+	}
+}
+
 // FileStrict sets the action when a field fails to convert to its expected type.
 //
 //	If true, then an error results.
@@ -250,23 +501,72 @@ func FileStringDelim(delim byte) FileOpt {
 	}
 }
 
+// FileThousandsSep sets the thousands separator to remove from numbers when reading, for example ',' or '.'.
+// Default is none.
+func FileThousandsSep(sep byte) FileOpt {
+	return func(f *Files) error {
+		f.thousandsSep = sep
+
+		return nil
+	}
+}
+
+// FileWorkers sets the # of goroutines Load uses for a separated file.  The file is split into chunks of
+// whole records that are parsed and converted concurrently.  The result is identical to loading with one worker.
+// Default is 1.
+func FileWorkers(n int) FileOpt {
+	return func(f *Files) error {
+		if n < 1 {
+			return fmt.Errorf("workers must be at least 1")
+		}
+
+		f.workers = n
+
+		return nil
+	}
+}
+
 // ***************** Read Methods *****************
 
-// Load loads the data into a slice of *Vector.
+// Load loads the data into a slice of *Vector, one for each of ColumnNames. It does not apply FileFilter.
 func (f *Files) Load() ([]*Vector, error) {
 	defer func() { _ = f.Close() }()
+
+	var (
+		memData []*Vector
+		e       error
+	)
+	if f.workers > 1 && f.FieldWidths() == nil {
+		return f.loadParallel()
+	}
+
+	if memData, e = f.LoadRows(0); e == io.EOF {
+		for _, dt := range f.ColumnTypes() {
+			memData = append(memData, MakeVector(dt, 0))
+		}
+
+		return memData, nil
+	}
+
+	return memData, e
+}
+
+// LoadRows loads up to n rows, or all remaining rows if n is 0, into a slice of *Vector, one for each of ColumnNames.
+// It returns io.EOF if no rows remain. FileMaxRows is applied only if there is no FileFilter.
+func (f *Files) LoadRows(n int) ([]*Vector, error) {
 	var memData []*Vector
-	for ind := range len(f.FieldNames()) {
-		memData = append(memData, MakeVector(f.FieldTypes()[ind], 0))
+	for _, dt := range f.ColumnTypes() {
+		memData = append(memData, MakeVector(dt, 0))
 	}
 
-	for {
+	rows := 0
+	for (n == 0 || rows < n) && !f.full() {
 		var (
-			row any
-			e1  error
+			vals   []string
+			quoted []bool
+			e1     error
 		)
-
-		if row, e1 = f.read(); e1 != nil {
+		if vals, quoted, e1 = f.fields(); e1 != nil {
 			if e1 == io.EOF {
 				break
 			}
@@ -274,48 +574,89 @@ func (f *Files) Load() ([]*Vector, error) {
 			return nil, e1
 		}
 
-		r := row.([]any)
-		for ind := range len(r) {
-			if e := memData[ind].Append(r[ind]); e != nil {
-				return nil, e
-			}
+		if f.skipped < f.skipRows {
+			f.skipped++
+			continue
 		}
+
+		var bad []BadValue
+		if e := f.push(memData, record{vals: vals, quoted: quoted, line: f.recLine}, &bad); e != nil {
+			return nil, e
+		}
+
+		if e := f.addBad(bad); e != nil {
+			return nil, e
+		}
+
+		rows++
+		f.loaded++
+	}
+
+	if rows == 0 {
+		return nil, io.EOF
 	}
 
 	return memData, nil
 }
 
-// Open opens fileName for reading/writing.  It examines the file for consistency with the parameters (e.g has header).
+// Open opens fileName for reading.  It examines the file for consistency with the parameters (e.g has header).
 // If needed, it determines and sets field names and types.
 func (f *Files) Open(fileName string) error {
-	if f.fieldNames != nil && f.fieldTypes != nil && len(f.fieldNames) != len(f.fieldTypes) {
-		return fmt.Errorf("fieldNames and fieldTypes not same length in Open")
+	if e := f.check(); e != nil {
+		return e
 	}
 
-	if f.fieldNames != nil && f.fieldWidths != nil && len(f.fieldNames) != len(f.fieldWidths) {
-		return fmt.Errorf("fieldNames and fieldWidths not same length in Open")
+	f.fileName = fileName
+
+	var e error
+	if f.file, e = os.Open(fileName); e != nil {
+		return e
 	}
 
-	if f.fieldTypes != nil && f.fieldWidths != nil && len(f.fieldTypes) != len(f.fieldWidths) {
-		return fmt.Errorf("fieldTypes and fieldWidths not same length in Open")
+	return f.openReader(f.file)
+}
+
+// OpenReader prepares to read from r, which need not be seekable, as Open does for a file.
+// Rows examined to determine data types are buffered in memory rather than re-read.  So that the whole input
+// is not held in memory, OpenReader examines at most the first 10,000 rows if FilePeek is 0.  The values that
+// follow are read as the types detected, as with any FilePeek.
+// Gzip and zstd input is decompressed.  Files does not close r.
+func (f *Files) OpenReader(r io.Reader) error {
+	if e := f.check(); e != nil {
+		return e
 	}
 
-	f.fileName = fileName
+	return f.openReader(r)
+}
 
-	var e error
-	if f.file, e = os.Open(fileName); e != nil {
+func (f *Files) openReader(r io.Reader) error {
+	f.peeked, f.skipped, f.loaded, f.lines, f.badValues = nil, 0, 0, 0, nil
+	detected := len(f.FieldTypes()) == 0
+	if e := f.decompress(r); e != nil {
+		return e
+	}
+
+	if e := f.decode(); e != nil {
 		return e
 	}
 
-	f.rdr = bufio.NewReader(f.file)
+	if f.sniff && f.FieldWidths() == nil {
+		f.sniffSep()
+	}
+
+	if f.sniff && !f.reopened {
+		if e := f.sniffHeader(); e != nil {
+			return e
+		}
+	}
 
 	if len(f.FieldNames()) == 0 && !f.header {
 		return fmt.Errorf("no field names specified and no header")
 	}
 
 	// skip first line if field names are supplied
-	if f.header && f.FieldNames() != nil {
-		if _, e1 := f.read(); e1 != nil {
+	if (!f.sniff || f.reopened) && f.header && f.FieldNames() != nil {
+		if _, _, e1 := f.fields(); e1 != nil {
 			return e1
 		}
 	}
@@ -326,14 +667,18 @@ func (f *Files) Open(fileName string) error {
 		}
 	}
 
+	f.setFormats()
+
 	if len(f.FieldTypes()) == 0 {
-		var keep bool
-		keep, f.strict = f.strict, false
-		f.strict = false
-		if e1 := f.detect(); e1 != nil {
+		var (
+			counts []*ctr
+			e1     error
+		)
+		if counts, _, e1 = f.detect(nil); e1 != nil {
 			return e1
 		}
-		f.strict = keep
+
+		f.setTypes(counts)
 	}
 
 	if len(f.FieldTypes()) != len(f.FieldNames()) {
@@ -344,45 +689,122 @@ func (f *Files) Open(fileName string) error {
 		return fmt.Errorf("field widths and field names aren't the same length")
 	}
 
+	// the records examined by detect weren't buffered, so start over now that the types are known
+	if detected && f.file != nil {
+		_ = f.Close()
+		f.reopened = true
+		defer func() { f.reopened = false }()
+
+		return f.Open(f.fileName)
+	}
+
+	return f.project()
+}
+
+// check verifies the field names, types and widths supplied are consistent.
+func (f *Files) check() error {
+	if f.fieldNames != nil && f.fieldTypes != nil && len(f.fieldNames) != len(f.fieldTypes) {
+		return fmt.Errorf("fieldNames and fieldTypes not same length in Open")
+	}
+
+	if f.fieldNames != nil && f.fieldWidths != nil && len(f.fieldNames) != len(f.fieldWidths) {
+		return fmt.Errorf("fieldNames and fieldWidths not same length in Open")
+	}
+
+	if f.fieldTypes != nil && f.fieldWidths != nil && len(f.fieldTypes) != len(f.fieldWidths) {
+		return fmt.Errorf("fieldTypes and fieldWidths not same length in Open")
+	}
+
 	return nil
 }
 
-// read reads a line of the file
-func (f *Files) read() (any, error) {
-	var (
-		vals []string
-		e    error
-	)
-	if f.FieldWidths() != nil {
-		if vals, e = f.readFixed(); e != nil {
-			return nil, e
+// addBad records bad values, writing them to the rejects file if there is one.  It returns an error if there
+// are more than maxErrors.
+func (f *Files) addBad(bad []BadValue) error {
+	for _, b := range bad {
+		f.badValues = append(f.badValues, b)
+
+		if f.rejects != "" {
+			if f.rejectsF == nil {
+				var e error
+				if f.rejectsF, e = os.Create(f.rejects); e != nil {
+					return e
+				}
+
+				if _, e = f.rejectsF.WriteString("file,line,column,raw,type\n"); e != nil {
+					return e
+				}
+			}
+
+			q := &Files{sep: ',', stringDelim: '"'}
+			if _, e := fmt.Fprintf(f.rejectsF, "%s,%d,%s,%s,%s\n", q.quote(b.File, false), b.Line, q.quote(b.Column, false),
+				q.quote(b.Raw, true), b.Type); e != nil {
+				return e
+			}
 		}
-	} else {
-		if vals, e = f.readSep(); e != nil {
-			return nil, e
+
+		if f.maxErrors > 0 && len(f.badValues) > f.maxErrors {
+			return fmt.Errorf("more than %d bad values, last is line %d, field %s, value %q", f.maxErrors, b.Line, b.Column, b.Raw)
 		}
 	}
 
-	if len(f.FieldTypes()) == 0 {
-		return vals, nil
+	return nil
+}
+
+// full returns true if FileMaxRows rows have been loaded and there is no filter.
+func (f *Files) full() bool {
+	return f.filter == "" && f.maxRows > 0 && f.loaded >= f.maxRows
+}
+
+// push converts a record and appends it to memData.  If reporting, values that fail to convert are appended to bad.
+func (f *Files) push(memData []*Vector, r record, bad *[]BadValue) error {
+	var (
+		row []any
+		e   error
+	)
+	if row, e = f.convert(r, bad); e != nil {
+		return e
+	}
+
+	for ind := range len(row) {
+		if e = memData[ind].push(row[ind]); e != nil {
+			return fmt.Errorf("field %s: %w", f.ColumnNames()[ind], e)
+		}
 	}
-	var out []any
 
-	for ind := range len(f.FieldNames()) {
+	return nil
+}
+
+// convert converts the fields of a record that are to be loaded to their data types.
+func (f *Files) convert(r record, bad *[]BadValue) ([]any, error) {
+	vals, quoted := r.vals, r.quoted
+	var out []any
+	for _, ind := range f.keep {
 		var (
 			x  any
 			ok bool
 		)
-		fld := vals[ind]
 
 		dt := f.FieldTypes()[ind]
-		v := f.smartTrim(fld, dt)
-		if x, ok = toDataType(v, dt); !ok {
-			switch f.strict {
+		v := vals[ind]
+		if !quoted[ind] || dt != DTstring {
+			v = strings.Trim(v, " ")
+		}
+
+		if !quoted[ind] && Has(v, f.na) {
+			out = append(out, f.defaultValue(dt))
+			continue
+		}
+
+		if x, ok = f.parse(v, ind, dt); !ok {
+			switch f.strict && !f.report {
 			case true:
 				return nil, fmt.Errorf("conversion failed in Files.Read,field: %s value: %v", f.fieldNames[ind], v)
 			case false:
 				x = f.defaultValue(f.FieldTypes()[ind])
+				if f.report {
+					*bad = append(*bad, BadValue{File: f.fileName, Line: r.line, Column: f.fieldNames[ind], Raw: v, Type: dt})
+				}
 			}
 		}
 
@@ -392,6 +814,38 @@ func (f *Files) read() (any, error) {
 	return out, nil
 }
 
+// fields reads the next record and returns its fields along with whether each was enclosed in string delimiters.
+// The delimiters are removed.
+func (f *Files) fields() (vals []string, quoted []bool, err error) {
+	if len(f.peeked) > 0 {
+		r := f.peeked[0]
+		f.peeked = f.peeked[1:]
+		f.recLine = r.line
+
+		return r.vals, r.quoted, nil
+	}
+
+	f.recLine = f.lines + 1
+	if f.FieldWidths() == nil {
+		return f.readSep()
+	}
+
+	if vals, err = f.readFixed(); err != nil {
+		return nil, nil, err
+	}
+
+	f.lines++
+
+	quoted = make([]bool, len(vals))
+	for ind, v := range vals {
+		if d := f.stringDelim; d != 0 && len(v) > 1 && v[0] == d && v[len(v)-1] == d {
+			vals[ind], quoted[ind] = v[1:len(v)-1], true
+		}
+	}
+
+	return vals, quoted, nil
+}
+
 func (f *Files) readFixed() ([]string, error) {
 	adder := 0
 	if f.eol != 0 {
@@ -399,13 +853,14 @@ func (f *Files) readFixed() ([]string, error) {
 	}
 
 	b := make([]byte, f.lineWidth+adder)
-	n, eOrEOF := f.file.Read(b)
+	n, eOrEOF := io.ReadFull(f.rdr, b)
 
-	if n == f.lineWidth+adder {
+	// the last line may lack an EOL
+	if n == f.lineWidth+adder || (n == f.lineWidth && eOrEOF == io.ErrUnexpectedEOF) {
 		return f.splitFixed(b), nil
 	}
 
-	if eOrEOF == nil {
+	if eOrEOF == nil || eOrEOF == io.ErrUnexpectedEOF {
 		return nil, io.EOF
 	}
 
@@ -414,34 +869,58 @@ func (f *Files) readFixed() ([]string, error) {
 
 func (f *Files) readHeader() error {
 	var (
-		x any
-		e error
+		names []string
+		e     error
 	)
 
-	if x, e = f.read(); e != nil {
+	if names, _, e = f.fields(); e != nil {
 		return e
 	}
 
-	f.fieldNames = x.([]string)
+	for ind := range names {
+		names[ind] = strings.Trim(names[ind], " ")
+	}
+
+	f.fieldNames = names
 
 	return nil
 }
 
-func (f *Files) readSep() ([]string, error) {
+// readSep reads a record of a separated file. A record continues onto following lines while a quoted field is open.
+func (f *Files) readSep() (vals []string, quoted []bool, err error) {
 	var (
 		line   string
 		eOrEOF error
 	)
 	if line, eOrEOF = f.rdr.ReadString(f.eol); (eOrEOF == io.EOF && line == "") || (eOrEOF != nil && eOrEOF != io.EOF) {
-		return nil, eOrEOF
+		return nil, nil, eOrEOF
+	}
+
+	f.lines++
+	rec := line
+	for {
+		var complete bool
+		if vals, quoted, complete = f.splitSep(f.dropEOL(rec)); complete {
+			break
+		}
+
+		if eOrEOF == io.EOF {
+			return nil, nil, fmt.Errorf("unterminated quoted field in record %s", rec)
+		}
+
+		if line, eOrEOF = f.rdr.ReadString(f.eol); eOrEOF != nil && eOrEOF != io.EOF {
+			return nil, nil, eOrEOF
+		}
+
+		f.lines++
+		rec += line
 	}
 
-	var vals []string
-	if vals = f.splitSep(f.dropEOL(line)); f.FieldNames() != nil && len(vals) != len(f.FieldNames()) {
-		return nil, fmt.Errorf("line %s has wrong number of fields", line)
+	if f.FieldNames() != nil && len(vals) != len(f.FieldNames()) {
+		return nil, nil, fmt.Errorf("line %s has wrong number of fields", rec)
 	}
 
-	return vals, nil
+	return vals, quoted, nil
 }
 
 // ***************** Write Methods *****************
@@ -456,17 +935,42 @@ func (f *Files) Create(fileName string) error {
 	return e
 }
 
-// Save saves df out to fileName.  The file must be created first.
+// Save saves df out to fileName.
 func (f *Files) Save(fileName string, df HasIter) error {
 	defer func() { _ = f.Close() }()
 
-	var (
-		fieldNames []string
-		fieldTypes []DataTypes
-	)
-	switch val := df.(type) {
-	case DF:
-		fieldNames = val.ColumnNames()
+	f.fileName = fileName
+	method := f.compression
+	if method == CompressNone {
+		switch filepath.Ext(fileName) {
+		case ".gz":
+			method = CompressGzip
+		case ".zst":
+			method = CompressZstd
+		}
+	}
+
+	var e error
+	if f.file, e = os.Create(fileName); e != nil {
+		return e
+	}
+
+	return f.saveWriter(f.file, df, method)
+}
+
+// SaveWriter writes df to w, compressed if FileCompression is set. Files does not close w.
+func (f *Files) SaveWriter(w io.Writer, df HasIter) error {
+	return f.saveWriter(w, df, f.compression)
+}
+
+func (f *Files) saveWriter(w io.Writer, df HasIter, method string) error {
+	var (
+		fieldNames []string
+		fieldTypes []DataTypes
+	)
+	switch val := df.(type) {
+	case DF:
+		fieldNames = val.ColumnNames()
 		fieldTypes, _ = val.ColumnTypes()
 	case Column:
 		fieldNames = []string{"Column"}
@@ -476,14 +980,24 @@ func (f *Files) Save(fileName string, df HasIter) error {
 		fieldTypes = []DataTypes{val.VectorType()}
 	}
 
+	if f.FieldWidths() != nil && len(f.FieldWidths()) != len(fieldNames) {
+		return fmt.Errorf("field widths and field names aren't the same length")
+	}
+
 	f.fieldNames = fieldNames
 	f.fieldTypes = fieldTypes
+	f.setFormats()
 
-	var e error
-	if f.file, e = os.Create(fileName); e != nil {
+	var (
+		zw io.WriteCloser
+		e  error
+	)
+	if zw, e = compressor(w, method); e != nil {
 		return e
 	}
 
+	f.wtr = bufio.NewWriter(zw)
+
 	if ex := f.writeHeader(fieldNames); ex != nil {
 		return ex
 	}
@@ -494,54 +1008,68 @@ func (f *Files) Save(fileName string, df HasIter) error {
 		}
 	}
 
-	return nil
+	if ex := f.wtr.Flush(); ex != nil {
+		return ex
+	}
+
+	return zw.Close()
 }
 
-// write writes a line to the file
+// write writes a record to the file. Strings are always enclosed in string delimiters, other values only
+// if they contain a separator, delimiter or line break. If there are field widths, fields are padded to their
+// widths instead.
 func (f *Files) write(v []any) error {
 	var line []byte
 	for ind := range len(v) {
-		var lx []byte
+		var (
+			fld        string
+			force, num bool
+		)
+
+		floatFormat, dateFormat := cmp.Or(f.colFmts[ind].float, f.floatFormat), cmp.Or(f.colFmts[ind].date, f.dateFormat)
 
 		switch d := v[ind].(type) {
 		case float64:
-			lx = []byte(fmt.Sprintf(f.floatFormat, d))
+			fld, num = f.decimal(fmt.Sprintf(floatFormat, d)), true
 		case int, int8, int16, int32, int64:
-			lx = []byte(fmt.Sprintf("%v", d))
+			fld, num = fmt.Sprintf("%v", d), true
 		case time.Time:
-			lx = []byte(d.Format(f.dateFormat))
+			fld = d.Format(dateFormat)
 		case string:
-			lx = []byte(d)
-			if f.stringDelim != 0 {
-				lx = append([]byte{f.stringDelim}, lx...)
-				lx = append(lx, f.stringDelim)
-			}
+			fld, force = d, true
 		case *float64:
-			lx = []byte(fmt.Sprintf(f.floatFormat, *d))
+			fld, num = f.decimal(fmt.Sprintf(floatFormat, *d)), true
 		case *int64:
-			lx = []byte(fmt.Sprintf("%v", *d))
+			fld, num = fmt.Sprintf("%v", *d), true
 		case *time.Time:
-			lx = []byte(d.Format(f.dateFormat))
+			fld = d.Format(dateFormat)
 		case *string:
-			lx = []byte(*d)
-			if f.stringDelim != 0 {
-				lx = append([]byte{f.stringDelim}, lx...)
-				lx = append(lx, f.stringDelim)
-			}
+			fld, force = *d, true
 		default:
-			lx = []byte("#err#")
+			fld = "#err#"
+		}
+
+		if f.FieldWidths() != nil {
+			var e error
+			if fld, e = f.fit(fld, ind, num, force); e != nil {
+				return e
+			}
+
+			line = append(line, fld...)
+			continue
 		}
-		line = append(line, lx...)
+
+		line = append(line, f.quote(fld, force)...)
 		if ind < len(v)-1 {
 			line = append(line, f.sep)
 		}
 	}
 
-	if _, e := f.file.Write(line); e != nil {
+	if _, e := f.wtr.Write(line); e != nil {
 		return e
 	}
 
-	_, e := f.file.Write([]byte{f.eol})
+	_, e := f.wtr.Write(f.lineEnd())
 
 	return e
 }
@@ -551,7 +1079,23 @@ func (f *Files) writeHeader(fieldNames []string) error {
 		return nil
 	}
 
-	if _, e := f.file.WriteString(strings.Join(fieldNames, string(rune(f.sep))) + string(rune(f.eol))); e != nil {
+	names := make([]string, len(fieldNames))
+	for ind, fn := range fieldNames {
+		names[ind] = f.quote(fn, false)
+		if f.FieldWidths() != nil {
+			var e error
+			if names[ind], e = f.fit(fn, ind, false, false); e != nil {
+				return e
+			}
+		}
+	}
+
+	sep := string(rune(f.sep))
+	if f.FieldWidths() != nil {
+		sep = ""
+	}
+
+	if _, e := f.wtr.WriteString(strings.Join(names, sep) + string(f.lineEnd())); e != nil {
 		return e
 	}
 
@@ -560,14 +1104,67 @@ func (f *Files) writeHeader(fieldNames []string) error {
 
 // ***************** Other Methods *****************
 
+// AddBadValues adds bad values to those returned by BadValues, as if f had found them, and writes them to the
+// FileRejects file.  It is used to collect the bad values found by copies of f that read other files.  It returns
+// an error if f now has more than the maximum set by FileReport.
+func (f *Files) AddBadValues(bad []BadValue) error {
+	return f.addBad(bad)
+}
+
+// Copy returns an unopened Files with the options of f, so several files can be read with the same options at
+// once.  f should not be open.  The field names, types and widths are copied as set by options; if f has
+// detected them by opening a file, the copy has those.  The copy doesn't write the FileRejects file: add its
+// BadValues to f with AddBadValues.
+func (f *Files) Copy() *Files {
+	c := *f
+	c.file, c.unzip, c.rdr, c.wtr, c.peeked = nil, nil, nil, nil, nil
+	c.rejects, c.rejectsF, c.badValues = "", nil, nil
+
+	return &c
+}
+
+// Close closes the file opened by Open or Save.
 func (f *Files) Close() error {
+	if f.rejectsF != nil {
+		_ = f.rejectsF.Close()
+		f.rejectsF = nil
+	}
+
+	if f.unzip != nil {
+		_ = f.unzip.Close()
+		f.unzip = nil
+	}
+
 	if f.file != nil {
-		return f.file.Close()
+		e := f.file.Close()
+		f.file = nil
+
+		return e
 	}
 
 	return nil
 }
 
+// ColumnNames returns the names of the columns Load returns: the field names, restricted to FileColumns if set.
+func (f *Files) ColumnNames() []string {
+	var names []string
+	for _, ind := range f.keep {
+		names = append(names, f.fieldNames[ind])
+	}
+
+	return names
+}
+
+// ColumnTypes returns the data types of the columns Load returns.
+func (f *Files) ColumnTypes() []DataTypes {
+	var dts []DataTypes
+	for _, ind := range f.keep {
+		dts = append(dts, f.fieldTypes[ind])
+	}
+
+	return dts
+}
+
 func (f *Files) FieldNames() []string {
 	return f.fieldNames
 }
@@ -580,8 +1177,87 @@ func (f *Files) FieldWidths() []int {
 	return f.fieldWidths
 }
 
+// BadValues returns the values that failed to convert when loading with FileReport or FileRejects.
+func (f *Files) BadValues() []BadValue {
+	return f.badValues
+}
+
+// Filter returns the condition set by FileFilter.
+func (f *Files) Filter() string {
+	return f.filter
+}
+
+// MaxRows returns the maximum # of rows to load set by FileMaxRows.  0 means no limit.
+func (f *Files) MaxRows() int {
+	return f.maxRows
+}
+
 // ***************** Other Unexported Methods *****************
 
+func checkFloatFormat(format string) error {
+	if ok, _ := regexp.MatchString("%[0-9]?[0-9]?.[0-9]?[0-9]?f", format); !ok {
+		return fmt.Errorf("invalid float format: %s", format)
+	}
+
+	return nil
+}
+
+// compressor wraps w in a compressor for method.
+func compressor(w io.Writer, method string) (io.WriteCloser, error) {
+	switch method {
+	case CompressGzip:
+		return gzip.NewWriter(w), nil
+	case CompressZstd:
+		return zstd.NewWriter(w)
+	default:
+		return nopCloser{w}, nil
+	}
+}
+
+// decompress sets the reader to r, decompressing it if it starts with gzip or zstd magic bytes.
+func (f *Files) decompress(r io.Reader) error {
+	br := bufio.NewReaderSize(r, sniffBytes)
+	magic, _ := br.Peek(len(zstdMagic))
+
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		var (
+			gz *gzip.Reader
+			e  error
+		)
+		if gz, e = gzip.NewReader(br); e != nil {
+			return e
+		}
+
+		f.rdr, f.unzip = bufio.NewReaderSize(gz, sniffBytes), gz
+	case bytes.HasPrefix(magic, zstdMagic):
+		var (
+			zs *zstd.Decoder
+			e  error
+		)
+		if zs, e = zstd.NewReader(br); e != nil {
+			return e
+		}
+
+		rc := zs.IOReadCloser()
+		f.rdr, f.unzip = bufio.NewReaderSize(rc, sniffBytes), rc
+	default:
+		f.rdr = br
+	}
+
+	return nil
+}
+
+// DefaultValue returns the value loaded for a field of type dt that fails to convert, as set by FileDefaultInt,
+// FileDefaultFloat, FileDefaultDate and FileDefaultString.  It returns nil for other types.
+func (f *Files) DefaultValue(dt DataTypes) any {
+	if dt != DTint && dt != DTfloat && dt != DTdate && dt != DTstring {
+		return nil
+	}
+
+	return f.defaultValue(dt)
+}
+
 func (f *Files) defaultValue(dt DataTypes) any {
 	switch dt {
 	case DTint:
@@ -597,68 +1273,198 @@ func (f *Files) defaultValue(dt DataTypes) any {
 	}
 }
 
-func (f *Files) detect() error {
-	counts := make([]*ctr, 0)
+// detect counts the types of the values of the first peek records.  If the input can't be re-read, as with
+// OpenReader, the records are buffered to be returned by later reads.  Otherwise, openReader re-opens the file,
+// so only the counts are kept.  first, if not nil, is a possible header: repeats is true if one of its values
+// occurs in its field of the records counted.
+func (f *Files) detect(first []string) (counts []*ctr, repeats bool, err error) {
+	counts = make([]*ctr, len(f.FieldNames()))
+	for ind := range counts {
+		counts[ind] = &ctr{}
+	}
+
+	// input that can't be re-read is buffered, so not all of it is examined
+	peek := f.peek
+	if peek == 0 && f.file == nil {
+		peek = readerPeek
+	}
 
+	var peeked []record
 	rn := 0
 	for {
 		var (
-			v    any
-			vals []string
-			e1   error
+			vals   []string
+			quoted []bool
+			e1     error
 		)
-		if v, e1 = f.read(); e1 != nil {
+		if vals, quoted, e1 = f.fields(); e1 != nil {
 			if e1 == io.EOF {
 				break
 			}
 
-			return e1
+			return nil, false, e1
 		}
 
-		vals = v.([]string)
-
 		if len(vals) != len(f.FieldNames()) {
-			return fmt.Errorf("inconsistent # of fields in file")
+			return nil, false, fmt.Errorf("inconsistent # of fields in file")
 		}
 
-		for ind := range len(vals) {
-			var (
-				dt DataTypes
-				e2 error
-			)
-			if _, dt, e2 = bestType(vals[ind], true); e2 != nil {
-				return e2
-			}
+		r := record{vals: vals, quoted: quoted, line: f.recLine}
+		if e1 = f.count(counts, r); e1 != nil {
+			return nil, false, e1
+		}
 
-			if len(counts) < ind+1 {
-				counts = append(counts, &ctr{})
-			}
+		for ind, v := range first {
+			repeats = repeats || strings.Trim(vals[ind], " ") == v
+		}
 
-			switch dt {
-			case DTint:
-				counts[ind].cInt++
-			case DTfloat:
-				counts[ind].cFloat++
-			case DTdate:
-				counts[ind].cDate++
-			default:
-				counts[ind].cString++
-			}
+		if f.file == nil {
+			peeked = append(peeked, r)
 		}
 
 		rn++
-		if f.peek > 0 && rn > f.peek {
+		if peek > 0 && rn > peek {
 			break
 		}
 	}
 
+	f.peeked = peeked
+
+	return counts, repeats, nil
+}
+
+// count adds the types of the values of r to counts.
+func (f *Files) count(counts []*ctr, r record) error {
+	vals, quoted := r.vals, r.quoted
+	for ind := range len(vals) {
+		v := strings.Trim(vals[ind], " ")
+		if !quoted[ind] && Has(v, f.na) {
+			continue
+		}
+
+		var (
+			dt DataTypes
+			e2 error
+		)
+		if _, dt, e2 = bestType(v, true); e2 != nil {
+			return e2
+		}
+
+		// numbers with thousands separators or decimal commas, which may be quoted
+		if n := f.number(v); n != v && (dt == DTstring || quoted[ind]) {
+			if _, dtn, _ := bestType(n, false); dtn == DTint || dtn == DTfloat {
+				dt = dtn
+			}
+		} else if quoted[ind] {
+			// a value enclosed in string delimiters is a string
+			dt = DTstring
+		}
+
+		if layout := f.colFmts[ind].date; layout != "" {
+			dt = DTstring
+			if _, e3 := time.Parse(layout, v); e3 == nil {
+				dt = DTdate
+			}
+		}
+
+		switch dt {
+		case DTint:
+			counts[ind].cInt++
+		case DTfloat:
+			counts[ind].cFloat++
+		case DTdate:
+			counts[ind].cDate++
+		default:
+			counts[ind].cString++
+		}
+	}
+
+	return nil
+}
+
+// setTypes sets the field types to the type most values of each field have.
+func (f *Files) setTypes(counts []*ctr) {
+	f.fieldTypes = nil
 	for ind := range len(counts) {
 		f.fieldTypes = append(f.fieldTypes, counts[ind].max())
 	}
+}
+
+// format returns the formats for field, creating them if needed.
+func (f *Files) format(field string) *colFormat {
+	if f.formats[field] == nil {
+		f.formats[field] = &colFormat{}
+	}
+
+	return f.formats[field]
+}
+
+// setFormats sets the per-field formats by position from the formats by name.
+func (f *Files) setFormats() {
+	f.colFmts = make([]colFormat, len(f.FieldNames()))
+	for ind, fn := range f.FieldNames() {
+		if cf := f.formats[fn]; cf != nil {
+			f.colFmts[ind] = *cf
+		}
+	}
+}
+
+// parse converts v, the value of field ind, to dt using the field's formats.
+func (f *Files) parse(v string, ind int, dt DataTypes) (any, bool) {
+	switch {
+	case dt == DTdate && f.colFmts[ind].date != "":
+		t, e := time.Parse(f.colFmts[ind].date, v)
+		return t, e == nil
+	case dt == DTint || dt == DTfloat:
+		return toDataType(f.number(v), dt)
+	default:
+		return toDataType(v, dt)
+	}
+}
+
+// number removes thousands separators from v and replaces a decimal comma with a period.
+func (f *Files) number(v string) string {
+	if f.thousandsSep != 0 {
+		v = strings.ReplaceAll(v, string(f.thousandsSep), "")
+	}
+
+	if f.decimalComma {
+		v = strings.Replace(v, ",", ".", 1)
+	}
+
+	return v
+}
 
-	_ = f.Close()
+// decimal replaces the decimal point in a formatted float with a comma if FileDecimalComma is set.
+func (f *Files) decimal(v string) string {
+	if f.decimalComma {
+		return strings.Replace(v, ".", ",", 1)
+	}
 
-	return f.Open(f.fileName)
+	return v
+}
+
+// project sets the indices of the columns to load.
+func (f *Files) project() error {
+	f.keep = nil
+	if f.columns == nil {
+		for ind := range len(f.FieldNames()) {
+			f.keep = append(f.keep, ind)
+		}
+
+		return nil
+	}
+
+	for _, cn := range f.columns {
+		ind := Position(cn, f.FieldNames())
+		if ind < 0 {
+			return fmt.Errorf("column %s not in file", cn)
+		}
+
+		f.keep = append(f.keep, ind)
+	}
+
+	return nil
 }
 
 func (f *Files) dropEOL(line string) string {
@@ -669,38 +1475,130 @@ func (f *Files) dropEOL(line string) string {
 	return line
 }
 
-func (f *Files) smartTrim(line string, dt DataTypes) string {
-	if dt != DTstring {
-		return strings.Trim(line, " ")
+// lineEnd returns the bytes that end a written line.
+func (f *Files) lineEnd() []byte {
+	if f.crlf {
+		return []byte("\r\n")
+	}
+
+	if f.eol == 0 {
+		return nil
+	}
+
+	return []byte{f.eol}
+}
+
+// fit pads fld to the width of field ind for a fixed-width file.  Numbers are right-aligned, other values
+// left-aligned.  Strings that are too long are truncated; other values that are too long are an error.
+func (f *Files) fit(fld string, ind int, num, truncate bool) (string, error) {
+	w := f.FieldWidths()[ind]
+	if len(fld) > w {
+		if !truncate {
+			return "", fmt.Errorf("value %s is wider than field %s, width %d", fld, f.fieldNames[ind], w)
+		}
+
+		// don't split a multi-byte character
+		for w > 0 && !utf8.RuneStart(fld[w]) {
+			w--
+		}
+
+		fld = fld[:w]
 	}
 
-	x := strings.Trim(line, string(f.stringDelim)+" ")
+	pad := strings.Repeat(" ", f.FieldWidths()[ind]-len(fld))
+	if num {
+		return pad + fld, nil
+	}
 
-	return x
+	return fld + pad, nil
 }
 
-func (f *Files) splitSep(line string) []string {
-	if !strings.Contains(line, string(f.stringDelim)) {
-		return strings.Split(line, string(f.sep))
+// quote encloses fld in string delimiters if force is true or fld contains a separator, delimiter or line break.
+// Embedded delimiters are doubled.
+func (f *Files) quote(fld string, force bool) string {
+	d := f.stringDelim
+	if d == 0 {
+		return fld
 	}
 
-	var split []string
-	in := false
-	start := 0
-	for ind := range len(line) {
-		if f.stringDelim != 0 && line[ind] == f.stringDelim {
-			in = !in
+	if !force && !strings.ContainsAny(fld, string([]byte{f.sep, d, '\r', '\n', f.eol})) {
+		return fld
+	}
+
+	dq := string(d)
+
+	return dq + strings.ReplaceAll(fld, dq, dq+dq) + dq
+}
+
+// splitSep splits a record into fields following RFC 4180. A field starting with the string delimiter is quoted:
+// it runs to the next unpaired delimiter and may contain separators, line breaks and doubled delimiters.
+// complete is false if the record ends inside a quoted field.
+func (f *Files) splitSep(rec string) (vals []string, quoted []bool, complete bool) {
+	d := f.stringDelim
+	if d == 0 || !strings.Contains(rec, string(d)) {
+		vals = strings.Split(strings.TrimSuffix(rec, "\r"), string(f.sep))
+		return vals, make([]bool, len(vals)), true
+	}
+
+	var fld strings.Builder
+	ind := 0
+	for {
+		// look past leading spaces for an opening delimiter
+		start := ind
+		for start < len(rec) && rec[start] == ' ' {
+			start++
 		}
 
-		if !in && line[ind] == f.sep {
-			split = append(split, line[start:ind])
-			start = ind + 1
+		q := start < len(rec) && rec[start] == d
+		if q {
+			ind = start + 1
+			closed := false
+			for ind < len(rec) && !closed {
+				switch {
+				case rec[ind] != d:
+					fld.WriteByte(rec[ind])
+				case ind+1 < len(rec) && rec[ind+1] == d:
+					fld.WriteByte(d)
+					ind++
+				default:
+					closed = true
+				}
+
+				ind++
+			}
+
+			if !closed {
+				return nil, nil, false
+			}
 		}
-	}
 
-	split = append(split, line[start:])
+		// unquoted text, or anything trailing a closing delimiter, runs to the separator
+		end := strings.IndexByte(rec[ind:], f.sep)
+		last := end < 0
+		if last {
+			end = len(rec) - ind
+		}
+
+		tail := rec[ind : ind+end]
+		if last {
+			tail = strings.TrimSuffix(tail, "\r")
+		}
+
+		if q {
+			tail = strings.TrimRight(tail, " ")
+		}
 
-	return split
+		fld.WriteString(tail)
+		vals = append(vals, fld.String())
+		quoted = append(quoted, q)
+		fld.Reset()
+
+		if last {
+			return vals, quoted, true
+		}
+
+		ind += end + 1
+	}
 }
 
 func (f *Files) splitFixed(b []byte) []string {
@@ -715,6 +1613,12 @@ func (f *Files) splitFixed(b []byte) []string {
 	return out
 }
 
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
+
 // ****************** Used by detect *********************
 
 type ctr struct {
@@ -725,6 +1629,10 @@ type ctr struct {
 }
 
 func (c *ctr) max() DataTypes {
+	if c.cInt+c.cFloat+c.cDate+c.cString == 0 {
+		return DTstring
+	}
+
 	switch m := maxInt(c.cInt, c.cFloat, c.cDate, c.cString); m {
 	case c.cDate:
 		return DTdate
@@ -748,3 +1656,261 @@ func maxInt(ints ...int) int {
 
 	return mx
 }
+
+// ****************** Parallel Load *********************
+
+// chunk is a run of whole records of a separated file.
+type chunk struct {
+	data  []byte
+	ends  []int // offset of the end of each record in data
+	first int   // # of records preceding the chunk, after those buffered by detect
+	line  int   // line on which the chunk starts
+
+	memData []*Vector
+	bad     []BadValue
+	err     error
+}
+
+// loadParallel loads a separated file by splitting it into chunks of records which are parsed by up to f.workers
+// goroutines and concatenated in order.
+func (f *Files) loadParallel() ([]*Vector, error) {
+	var memData []*Vector
+	for _, dt := range f.ColumnTypes() {
+		memData = append(memData, MakeVector(dt, 0))
+	}
+
+	// records buffered by detect
+	for len(f.peeked) > 0 && !f.full() {
+		r := f.peeked[0]
+		f.peeked = f.peeked[1:]
+		if f.skipped < f.skipRows {
+			f.skipped++
+			continue
+		}
+
+		var bad []BadValue
+		if e := f.push(memData, r, &bad); e != nil {
+			return nil, e
+		}
+
+		if e := f.addBad(bad); e != nil {
+			return nil, e
+		}
+
+		f.loaded++
+	}
+
+	// the remaining rows to skip and load
+	skip, limit := f.skipRows-f.skipped, -1
+	if f.maxRows > 0 {
+		limit = f.maxRows - f.loaded
+	}
+
+	var (
+		chunks []*chunk
+		wg     sync.WaitGroup
+		e      error
+	)
+	sem := make(chan struct{}, f.workers)
+	sc := &scanner{f: f, line: f.lines + 1}
+	for limit != 0 {
+		var c *chunk
+		if c, e = sc.next(); e != nil || c == nil {
+			break
+		}
+
+		chunks = append(chunks, c)
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			c.parse(f, skip, limit)
+		}()
+
+		if limit > 0 && c.first+len(c.ends) >= skip+limit {
+			break
+		}
+	}
+
+	wg.Wait()
+
+	if e != nil {
+		return nil, e
+	}
+
+	for _, c := range chunks {
+		if ex := f.addBad(c.bad); ex != nil {
+			return nil, ex
+		}
+
+		if c.err != nil {
+			return nil, c.err
+		}
+
+		for ind, v := range c.memData {
+			if ex := memData[ind].AppendVector(v); ex != nil {
+				return nil, ex
+			}
+		}
+
+		f.skipped += min(skip, c.first+len(c.ends)) - min(skip, c.first)
+		f.loaded += c.memData[0].Len()
+	}
+
+	return memData, nil
+}
+
+// parse parses and converts the records of c, skipping the first skip records of the file and stopping after
+// limit records have been loaded, if limit is not negative.
+func (c *chunk) parse(f *Files, skip, limit int) {
+	defer func() { c.data = nil }()
+
+	for _, dt := range f.ColumnTypes() {
+		c.memData = append(c.memData, MakeVector(dt, 0))
+	}
+
+	start, line := 0, c.line
+	for ind, end := range c.ends {
+		rec := string(c.data[start:end])
+		start = end
+		recLine := line
+		line += strings.Count(rec, string(f.eol))
+
+		rn := c.first + ind
+		if limit >= 0 && rn-skip >= limit {
+			break
+		}
+
+		// skipped records are checked, as the serial loader does
+		vals, quoted, complete := f.splitSep(f.dropEOL(rec))
+		if !complete {
+			c.err = fmt.Errorf("unterminated quoted field in record %s", rec)
+			return
+		}
+
+		if len(vals) != len(f.FieldNames()) {
+			c.err = fmt.Errorf("line %s has wrong number of fields", rec)
+			return
+		}
+
+		if rn < skip {
+			continue
+		}
+
+		if e := f.push(c.memData, record{vals: vals, quoted: quoted, line: recLine}, &c.bad); e != nil {
+			c.err = e
+			return
+		}
+	}
+}
+
+// scanner splits a separated file into chunks of whole records.  It tracks quoting as splitSep does, so a line
+// break inside a quoted field doesn't end a record.
+type scanner struct {
+	f       *Files
+	rest    []byte // bytes read past the end of the last chunk
+	state   int    // quoting state at the end of rest
+	records int    // # of records in chunks so far
+	line    int    // line on which the next chunk starts
+	eof     bool
+}
+
+// scanner states
+const (
+	scanStart    = iota // start of a field
+	scanBare            // in an unquoted field
+	scanQuoted          // in a quoted field
+	scanEndQuote        // read a delimiter in a quoted field: either the closing delimiter or the first of a pair
+)
+
+// next returns the next chunk, or nil at the end of the file.
+func (sc *scanner) next() (*chunk, error) {
+	f := sc.f
+	data := sc.rest
+	scanned := len(data)
+	c := &chunk{first: sc.records, line: sc.line}
+	buf := make([]byte, min(f.chunkSize, 64*1024))
+	for !sc.eof && (len(c.ends) == 0 || len(data) < f.chunkSize) {
+		n, e := f.rdr.Read(buf)
+		data = append(data, buf[:n]...)
+		if e == io.EOF {
+			sc.eof = true
+		} else if e != nil {
+			return nil, e
+		}
+
+		for ind := scanned; ind < len(data); ind++ {
+			if sc.step(data[ind]) {
+				c.ends = append(c.ends, ind+1)
+			}
+		}
+
+		scanned = len(data)
+	}
+
+	// a final record without an EOL
+	last := 0
+	if len(c.ends) > 0 {
+		last = c.ends[len(c.ends)-1]
+	}
+
+	if sc.eof && last < len(data) {
+		c.ends = append(c.ends, len(data))
+		last = len(data)
+	}
+
+	if len(c.ends) == 0 {
+		return nil, nil
+	}
+
+	sc.rest = append([]byte(nil), data[last:]...)
+	sc.records += len(c.ends)
+	c.data = data[:last]
+	sc.line += bytes.Count(c.data, []byte{f.eol})
+
+	return c, nil
+}
+
+// step advances the quoting state over b and returns true if b ends a record.
+func (sc *scanner) step(b byte) bool {
+	f := sc.f
+	d := f.stringDelim
+	switch sc.state {
+	case scanStart:
+		switch {
+		case b == f.eol:
+			return true
+		case d != 0 && b == d:
+			sc.state = scanQuoted
+		case b == f.sep || b == ' ':
+		default:
+			sc.state = scanBare
+		}
+	case scanBare:
+		switch b {
+		case f.eol:
+			sc.state = scanStart
+			return true
+		case f.sep:
+			sc.state = scanStart
+		}
+	case scanQuoted:
+		if b == d {
+			sc.state = scanEndQuote
+		}
+	case scanEndQuote:
+		switch b {
+		case d:
+			sc.state = scanQuoted
+		case f.eol:
+			sc.state = scanStart
+			return true
+		case f.sep:
+			sc.state = scanStart
+		default:
+			sc.state = scanBare
+		}
+	}
+
+	return false
+}
@@ -53,12 +53,17 @@ type FnReturn struct {
 //	inputs - inputs to fn. If the inputs belong to a DF.
 func RunDFfn(fn Fn, df DF, inputs []Column) (Column, error) {
 	info := fn(true, nil)
-	if !info.Varying && info.Inputs != nil && len(inputs) != len(info.Inputs[0]) {
-		return nil, fmt.Errorf("got %d arguments to %s, expected %d", len(inputs), info.Name, len(info.Inputs[0]))
+	if e := checkArgs(info, len(inputs)); e != nil {
+		return nil, e
 	}
 
-	if info.Varying && info.Inputs != nil && len(inputs) < len(info.Inputs[0]) {
-		return nil, fmt.Errorf("need at least %d arguments to %s", len(inputs), info.Name)
+	var dts []DataTypes
+	for _, inp := range inputs {
+		dts = append(dts, inp.DataType())
+	}
+
+	if _, e := matchSignature(info, dts); e != nil {
+		return nil, e
 	}
 
 	var fnR *FnReturn
@@ -69,6 +74,26 @@ func RunDFfn(fn Fn, df DF, inputs []Column) (Column, error) {
 	return fnR.Value, nil
 }
 
+// checkArgs checks that nArgs is a valid number of arguments for the function described by info.
+func checkArgs(info *FnReturn, nArgs int) error {
+	var msg string
+	if !info.Varying && info.Inputs != nil && nArgs != len(info.Inputs[0]) {
+		msg = fmt.Sprintf("got %d arguments to %s, expected %d", nArgs, info.Name, len(info.Inputs[0]))
+	}
+
+	if info.Varying && info.Inputs != nil && nArgs < len(info.Inputs[0]) {
+		msg = fmt.Sprintf("need at least %d arguments to %s", len(info.Inputs[0]), info.Name)
+	}
+
+	if msg != "" {
+		pe := newParseError(info.Name, msg)
+		pe.Expected = signatures(info)
+		return pe
+	}
+
+	return nil
+}
+
 // *********
 
 // FnSpec specifies a function that the parser will have access to.
@@ -132,18 +157,16 @@ func LoadFunctions(fns string) Fmap {
 	m := make(Fmap)
 
 	for spec := range strings.SplitSeq(fns, "\n") {
-		details := strings.Split(spec, ":")
-		if len(details) != 6 {
+		if len(strings.Split(spec, ":")) != 6 {
 			continue
 		}
 
-		s := &FnSpec{
-			Name:     details[0],
-			FnDetail: details[1],
-			Inputs:   parseInputs(details[2]),
-			Outputs:  parseOutputs(details[3]),
-			IsScalar: details[4][0] == 'S',
-			Varying:  details[5][0] == 'Y',
+		var (
+			s *FnSpec
+			e error
+		)
+		if s, e = ParseFnSpec(spec); e != nil {
+			continue
 		}
 
 		m[s.Name] = s
@@ -152,6 +175,79 @@ func LoadFunctions(fns string) Fmap {
 	return m
 }
 
+// ParseFnSpec parses a single function specification. The format is the same as a line
+// of the file read by LoadFunctions:
+//
+//	name:detail:inputs:outputs:C|S:Y|N
+func ParseFnSpec(spec string) (*FnSpec, error) {
+	details := strings.Split(strings.TrimSpace(spec), ":")
+	if len(details) != 6 {
+		return nil, fmt.Errorf("function spec must have 6 fields, got %d: %s", len(details), spec)
+	}
+
+	if details[0] == "" || validName(details[0]) != nil {
+		return nil, fmt.Errorf("invalid function name in spec: %s", spec)
+	}
+
+	if details[1] == "" {
+		return nil, fmt.Errorf("empty function detail in spec: %s", spec)
+	}
+
+	if details[4] != "C" && details[4] != "S" {
+		return nil, fmt.Errorf("return type must be C or S in spec: %s", spec)
+	}
+
+	if details[5] != "Y" && details[5] != "N" {
+		return nil, fmt.Errorf("varying must be Y or N in spec: %s", spec)
+	}
+
+	s := &FnSpec{
+		Name:     details[0],
+		FnDetail: details[1],
+		Inputs:   parseInputs(details[2]),
+		Outputs:  parseOutputs(details[3]),
+		IsScalar: details[4][0] == 'S',
+		Varying:  details[5][0] == 'Y',
+	}
+
+	if e := s.check(); e != nil {
+		return nil, e
+	}
+
+	return s, nil
+}
+
+// check verifies the inputs and outputs of the spec are consistent.
+func (s *FnSpec) check() error {
+	if s.Name == "" || s.FnDetail == "" {
+		return fmt.Errorf("function spec needs a name and detail")
+	}
+
+	if len(s.Outputs) == 0 {
+		return fmt.Errorf("function %s has no outputs", s.Name)
+	}
+
+	if len(s.Inputs) > 0 && len(s.Inputs) != len(s.Outputs) {
+		return fmt.Errorf("function %s has %d input sets but %d outputs", s.Name, len(s.Inputs), len(s.Outputs))
+	}
+
+	for _, dt := range s.Outputs {
+		if dt == DTunknown {
+			return fmt.Errorf("unknown output type in function %s", s.Name)
+		}
+	}
+
+	for _, inp := range s.Inputs {
+		for _, dt := range inp {
+			if dt == DTunknown {
+				return fmt.Errorf("unknown input type in function %s", s.Name)
+			}
+		}
+	}
+
+	return nil
+}
+
 func parseInputs(inp string) [][]DataTypes {
 	var outDT [][]DataTypes
 	dts := strings.Split(inp, "{")
@@ -8,6 +8,7 @@ import (
 	"strconv"
 	"strings"
 	"time"
+	"unicode"
 )
 
 // DateFormats is list of available formats for dates.
@@ -48,6 +49,98 @@ func PrettyPrint(header []string, cols ...any) string {
 	return out
 }
 
+// PrettySQL formats qry with each clause on its own line and subqueries indented.  Only the white space
+// outside of quotes is changed.
+func PrettySQL(qry string) string {
+	const indent = "  "
+
+	// clauses start a new line
+	clauses := []string{"WITH", "SELECT", "FROM", "WHERE", "GROUP", "HAVING", "ORDER", "LIMIT", "UNION"}
+	joinTypes := []string{"INNER", "LEFT", "RIGHT", "FULL", "CROSS", "OUTER", "JOIN"}
+	// these are followed by a space before a parenthesis, unlike function names
+	keywords := []string{"AS", "IN", "AND", "OR", "NOT", "ON", "EXISTS", "ALL", "WHEN", "THEN", "ELSE", "BY", "USING"}
+
+	var (
+		b        strings.Builder
+		subquery []bool         // for each open parenthesis, true if it starts a subquery
+		clause   = []string{""} // current clause at each depth
+		prev     string
+	)
+
+	depth, space := 0, false
+	toks := sqlTokens(qry)
+	for ind, tok := range toks {
+		upper, prevUpper := strings.ToUpper(tok), strings.ToUpper(prev)
+		inFn := len(subquery) > 0 && !subquery[len(subquery)-1]
+
+		switch tok {
+		case "(":
+			sub := ind+1 < len(toks) && Has(strings.ToUpper(toks[ind+1]), []string{"SELECT", "WITH"})
+			if space && (sub || !isWord(prev) || Has(prevUpper, keywords) || Has(prevUpper, clauses)) {
+				b.WriteString(" ")
+			}
+
+			b.WriteString("(")
+			subquery = append(subquery, sub)
+			if sub {
+				depth++
+				clause = append(clause, "")
+			}
+
+			space = false
+		case ")":
+			if n := len(subquery); n > 0 {
+				if subquery[n-1] {
+					depth--
+					clause = clause[:len(clause)-1]
+					b.WriteString("\n" + strings.Repeat(indent, depth))
+				}
+
+				subquery = subquery[:n-1]
+			}
+
+			b.WriteString(")")
+			space = true
+		case ",":
+			b.WriteString(",")
+			space = true
+			if !inFn && clause[depth] == "SELECT" {
+				b.WriteString("\n" + strings.Repeat(indent, depth+1))
+				space = false
+			}
+		default:
+			newClause := false
+			if !inFn {
+				switch {
+				case Has(upper, joinTypes):
+					// LEFT OUTER JOIN is one clause. LEFT( is a function.
+					newClause = !Has(prevUpper, joinTypes) && (ind+1 == len(toks) || toks[ind+1] != "(")
+				default:
+					newClause = Has(upper, clauses)
+				}
+			}
+
+			switch {
+			case newClause && b.Len() > 0:
+				b.WriteString("\n" + strings.Repeat(indent, depth))
+			case space:
+				b.WriteString(" ")
+			}
+
+			if newClause {
+				clause[depth] = upper
+			}
+
+			b.WriteString(tok)
+			space = true
+		}
+
+		prev = tok
+	}
+
+	return b.String()
+}
+
 // StringSlice converts inVal to a slice of strings, the first element is the header.
 // inVal is expected to be a slice of float64, int, string or time.Time
 func StringSlice(header string, inVal any) []string {
@@ -438,3 +531,65 @@ func selectFormat(x []float64) string {
 	format := "%." + fmt.Sprintf("%d", dp) + "f"
 	return format
 }
+
+// sqlTokens splits qry into words, parentheses and commas.  Quoted strings and identifiers are kept whole.
+func sqlTokens(qry string) []string {
+	var (
+		toks []string
+		tok  strings.Builder
+	)
+
+	flush := func() {
+		if tok.Len() > 0 {
+			toks = append(toks, tok.String())
+			tok.Reset()
+		}
+	}
+
+	rs := []rune(qry)
+	for ind := 0; ind < len(rs); ind++ {
+		r := rs[ind]
+		switch {
+		case unicode.IsSpace(r):
+			flush()
+		case r == '(' || r == ')' || r == ',':
+			flush()
+			toks = append(toks, string(r))
+		case r == '\'' || r == '"' || r == '`':
+			tok.WriteRune(r)
+			for ind++; ind < len(rs); ind++ {
+				tok.WriteRune(rs[ind])
+
+				// escaped quotes are either doubled or preceded by a backslash
+				if (rs[ind] == '\\' && r == '\'') || (rs[ind] == r && ind+1 < len(rs) && rs[ind+1] == r) {
+					if ind++; ind < len(rs) {
+						tok.WriteRune(rs[ind])
+					}
+
+					continue
+				}
+
+				if rs[ind] == r {
+					break
+				}
+			}
+		default:
+			tok.WriteRune(r)
+		}
+	}
+
+	flush()
+
+	return toks
+}
+
+// isWord returns true if s starts with a letter, digit, underscore or quote.
+func isWord(s string) bool {
+	if s == "" {
+		return false
+	}
+
+	r := []rune(s)[0]
+
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '"' || r == '`'
+}
@@ -5,10 +5,13 @@ import (
 	"encoding/binary"
 	"fmt"
 	"hash/fnv"
+	"io"
 	"iter"
 	"maps"
+	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	d "github.com/invertedv/df"
@@ -24,6 +27,9 @@ type DF struct {
 	*d.DFcore
 }
 
+// ctxRows is how often (in rows) long loops check whether the context of the DF is done.
+const ctxRows = 4096
+
 type groups map[uint64]*groupVal
 
 type groupVal struct {
@@ -91,8 +97,15 @@ func NewDF(input any, opts ...d.DFopt) (*DF, error) {
 		}
 	case d.HasMQdlct:
 		// more efficient than the next case
-		var e error
-		if df, e = DBload(inp.MakeQuery(), inp.Dialect(), opts...); e != nil {
+		var (
+			qry string
+			e   error
+		)
+		if qry, e = inp.MakeQueryE(); e != nil {
+			return nil, e
+		}
+
+		if df, e = DBload(qry, inp.Dialect(), opts...); e != nil {
 			return nil, e
 		}
 	case d.DF:
@@ -189,7 +202,7 @@ func DBload(qry string, dlct *d.Dialect, opts ...d.DFopt) (*DF, error) {
 	)
 
 	var memData []*d.Vector
-	if memData, columnNames, columnTypes, e = dlct.Load(qry); e != nil {
+	if memData, columnNames, columnTypes, e = dlct.LoadContext(d.OptsContext(opts...), qry); e != nil {
 		return nil, e
 	}
 
@@ -220,8 +233,24 @@ func DBload(qry string, dlct *d.Dialect, opts ...d.DFopt) (*DF, error) {
 	return memDF, nil
 }
 
-// *FileLoad loads a *DF from a *d.Files struct.
+// TableStats loads a *DF with a row for each table in schema (the database, for ClickHouse).  Its columns are the
+// table's name and the database's estimates of its rows, columns and bytes.  If schema is empty, the current schema
+// is used.  See Dialect.TableStatsQuery.
+func TableStats(dlct *d.Dialect, schema string, opts ...d.DFopt) (*DF, error) {
+	if dlct == nil {
+		return nil, fmt.Errorf("nil dialect to TableStats")
+	}
+
+	return DBload(dlct.TableStatsQuery(schema), dlct, opts...)
+}
+
+// *FileLoad loads a *DF from a *d.Files struct. If the *d.Files has a filter, rows are read in batches
+// and only those satisfying the filter are kept.
 func FileLoad(f *d.Files, opts ...d.DFopt) (*DF, error) {
+	if f.Filter() != "" {
+		return fileFilter(f, opts...)
+	}
+
 	var (
 		memData []*d.Vector
 		e       error
@@ -230,30 +259,140 @@ func FileLoad(f *d.Files, opts ...d.DFopt) (*DF, error) {
 		return nil, e
 	}
 
-	var memDF *DF
-	for ind := range len(f.FieldNames()) {
-		var col *Col
+	return fileDF(f.ColumnNames(), memData, opts...)
+}
+
+// FileErrors returns the values that failed to convert when f was loaded with d.FileReport or d.FileRejects.
+// The *DF has columns file, line, column, raw and type.  It returns an error if there were none.
+func FileErrors(f *d.Files, opts ...d.DFopt) (*DF, error) {
+	bad := f.BadValues()
+	if len(bad) == 0 {
+		return nil, fmt.Errorf("no bad values")
+	}
+
+	var (
+		lines                    []int
+		files, columns, raw, dts []string
+	)
+	for _, b := range bad {
+		files = append(files, b.File)
+		lines = append(lines, b.Line)
+		columns = append(columns, b.Column)
+		raw = append(raw, b.Raw)
+		dts = append(dts, b.Type.String())
+	}
 
-		if col, e = NewCol(memData[ind], d.ColName(f.FieldNames()[ind])); e != nil {
+	var memData []*d.Vector
+	for _, data := range []any{files, lines, columns, raw, dts} {
+		var (
+			v *d.Vector
+			e error
+		)
+		if v, e = d.NewVector(data, d.WhatAmI(data)); e != nil {
 			return nil, e
 		}
 
-		if ind == 0 {
-			if memDF, e = NewDFcol([]*Col{col}, opts...); e != nil {
-				return nil, e
+		memData = append(memData, v)
+	}
+
+	return fileDF([]string{"file", "line", "column", "raw", "type"}, memData, opts...)
+}
+
+// FilesLoad loads the files matching pattern, as defined by filepath.Match, into one *DF.
+//
+//	pattern      - files to load, in the order filepath.Glob returns them.
+//	f            - options for reading each file.  Each file is read by a copy of f, so f itself isn't opened.
+//	               Options such as d.FileMaxRows and d.FileSkipRows apply to each file.  The bad values
+//	               of all the files are available from f.BadValues.
+//	sourceColumn - if not empty, a column of this name with the file each row came from is added.
+//	concurrent   - # of files to load at once.
+//
+// The files must have the same columns, though not necessarily in the same order.  A column that is int in some
+// files and float in others is float, with the int default value (e.g. a missing value) becoming the float default.
+func FilesLoad(pattern string, f *d.Files, sourceColumn string, concurrent int, opts ...d.DFopt) (*DF, error) {
+	defer func() { _ = f.Close() }()
+
+	var (
+		fileNames []string
+		e         error
+	)
+	if fileNames, e = filepath.Glob(pattern); e != nil {
+		return nil, e
+	}
+
+	if len(fileNames) == 0 {
+		return nil, fmt.Errorf("no files match %s", pattern)
+	}
+
+	if concurrent < 1 {
+		return nil, fmt.Errorf("concurrent files must be at least 1")
+	}
+
+	dfs := make([]*DF, len(fileNames))
+	copies := make([]*d.Files, len(fileNames))
+	errs := make([]error, len(fileNames))
+	sem := make(chan struct{}, concurrent)
+	var wg sync.WaitGroup
+	for ind, fileName := range fileNames {
+		copies[ind] = f.Copy()
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			if errs[ind] = copies[ind].Open(fileName); errs[ind] != nil {
+				return
 			}
 
-			continue
-		}
+			dfs[ind], errs[ind] = FileLoad(copies[ind])
+		}()
+	}
 
-		if ex := memDF.AppendColumn(col, false); ex != nil {
+	wg.Wait()
+
+	// bad values are reported in file order
+	for ind, fileName := range fileNames {
+		if ex := f.AddBadValues(copies[ind].BadValues()); ex != nil {
 			return nil, ex
 		}
+
+		if errs[ind] != nil {
+			return nil, fmt.Errorf("loading %s: %w", fileName, errs[ind])
+		}
 	}
 
-	memDF.row = -1
+	var memData []*d.Vector
+	names := dfs[0].ColumnNames()
+	for _, cn := range names {
+		var v *d.Vector
+		if v, e = stackColumn(cn, fileNames, dfs, f); e != nil {
+			return nil, e
+		}
 
-	return memDF, nil
+		memData = append(memData, v)
+	}
+
+	if sourceColumn != "" {
+		if d.Has(sourceColumn, names) {
+			return nil, fmt.Errorf("source column %s is already a column", sourceColumn)
+		}
+
+		var source []string
+		for ind, fileName := range fileNames {
+			for range dfs[ind].RowCount() {
+				source = append(source, fileName)
+			}
+		}
+
+		var v *d.Vector
+		if v, e = d.NewVector(source, d.DTstring); e != nil {
+			return nil, e
+		}
+
+		names = append(names, sourceColumn)
+		memData = append(memData, v)
+	}
+
+	return fileDF(names, memData, opts...)
 }
 
 // ***************** Methods *****************
@@ -297,10 +436,6 @@ func (f *DF) AppendColumn(col d.Column, replace bool) error {
 	if cx2, ok := col.(*d.Scalar); ok || (col.Len() == 1 && f.RowCount() > 1) {
 		if ok {
 			val = cx2.Data().Element(0)
-			// a double single quote means keep a single quote in the string
-			if s, ok := val.(string); ok {
-				val = strings.ReplaceAll(s, "''", "'")
-			}
 		}
 
 		v := d.MakeVector(col.DataType(), f.RowCount())
@@ -361,6 +496,11 @@ func (f *DF) AppendDF(df d.DF) (d.DF, error) {
 //	groupBy - comma-separated list of fields to group on.  If groupBy is empty, then the output will have 1 row.
 //	fns     - functions to calculate on the By groups.
 func (f *DF) By(groupBy string, fns ...string) (d.DF, error) {
+	return f.ByParams(groupBy, nil, fns...)
+}
+
+// ByParams is By with values for the parameters (e.g. @rate) in fns.
+func (f *DF) ByParams(groupBy string, params map[string]any, fns ...string) (d.DF, error) {
 	if fns == nil {
 		return nil, fmt.Errorf("must have at least on function in By")
 	}
@@ -400,14 +540,30 @@ func (f *DF) By(groupBy string, fns ...string) (d.DF, error) {
 		grp[0] = &groupVal{groupDF: f}
 	}
 
-	var left []string
+	// compile the functions once rather than for each group
+	var (
+		left  []string
+		exprs []*d.Expr
+	)
 	for ind := range len(fns) {
-		lr := strings.Split(fns[ind], ":=")
-		left = append(left, strings.ReplaceAll(lr[0], " ", ""))
+		var (
+			ex *d.Expr
+			e0 error
+		)
+		if ex, e0 = d.Compile(fns[ind], f.Fns()); e0 != nil {
+			return nil, e0
+		}
+
+		exprs = append(exprs, ex.Bind(params))
+		left = append(left, ex.Name())
 	}
 
 	// run through the groups
 	for _, v := range grp {
+		if e := f.Context().Err(); e != nil {
+			return nil, e
+		}
+
 		// run through the functions to calculate
 		for ind := range len(fns) {
 			// populate group column values on first iteration
@@ -420,7 +576,7 @@ func (f *DF) By(groupBy string, fns ...string) (d.DF, error) {
 			}
 
 			// run function
-			if e1 := d.Parse(v.groupDF, fns[ind]); e1 != nil {
+			if e1 := exprs[ind].Eval(v.groupDF); e1 != nil {
 				return nil, e1
 			}
 
@@ -458,7 +614,7 @@ func (f *DF) By(groupBy string, fns ...string) (d.DF, error) {
 		outDF *DF
 		e4    error
 	)
-	if outDF, e4 = NewDFcol(cols, d.DFsetFns(f.Fns())); e4 != nil {
+	if outDF, e4 = NewDFcol(cols, d.DFsetFns(f.Fns()), d.DFcontext(f.Context())); e4 != nil {
 		return nil, e4
 	}
 
@@ -612,7 +768,7 @@ func (f *DF) Interp(points d.HasIter, xSfield, xIfield, yfield, outField string)
 		idf *DF
 		e1  error
 	)
-	if idf, e1 = NewDF(points, d.DFsetFns(f.Fns())); e1 != nil {
+	if idf, e1 = NewDF(points, d.DFsetFns(f.Fns()), d.DFcontext(f.Context())); e1 != nil {
 		return nil, e1
 	}
 
@@ -742,7 +898,7 @@ func (f *DF) Join(df d.HasIter, joinOn string) (d.DF, error) {
 		e      error
 	)
 
-	if fRight, e = NewDF(df, d.DFsetFns(f.Fns())); e != nil {
+	if fRight, e = NewDF(df, d.DFsetFns(f.Fns()), d.DFcontext(f.Context())); e != nil {
 		return nil, fmt.Errorf("invalid input to Join")
 	}
 
@@ -787,7 +943,12 @@ func (f *DF) Join(df d.HasIter, joinOn string) (d.DF, error) {
 	nextJoinRight := subset(nextRight, colsRight)
 	lh := -1 // start index of a block of left df that have same join keys
 
-	for {
+	ctx := f.Context()
+	for loop := 0; ; loop++ {
+		if loop%ctxRows == 0 && ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
 		if rowCompare(leftJoin, rightJoin, "eq") {
 			if e := appendRow(outCols, leftRow, rightRow, colsRight); e != nil {
 				return nil, e
@@ -854,7 +1015,7 @@ func (f *DF) Join(df d.HasIter, joinOn string) (d.DF, error) {
 		break
 	}
 
-	outDF, e1 := NewDFcol(outCols, d.DFsetFns(f.Fns()))
+	outDF, e1 := NewDFcol(outCols, d.DFsetFns(f.Fns()), d.DFcontext(ctx))
 
 	return outDF, e1
 }
@@ -1017,7 +1178,20 @@ func (f *DF) Table(cols string) (d.DF, error) {
 
 // Where subsets f to rows where condition is true.
 func (f *DF) Where(condition string) (d.DF, error) {
-	if e := d.Parse(f, "wherec:="+condition); e != nil {
+	return f.WhereParams(condition, nil)
+}
+
+// WhereParams is Where with values for the parameters (e.g. @rate) in condition.
+func (f *DF) WhereParams(condition string, params map[string]any) (d.DF, error) {
+	var (
+		ex *d.Expr
+		e0 error
+	)
+	if ex, e0 = d.Compile("wherec:="+condition, f.Fns()); e0 != nil {
+		return nil, e0
+	}
+
+	if e := ex.Bind(params).Eval(f); e != nil {
 		return nil, e
 	}
 
@@ -1044,6 +1218,182 @@ func (f *DF) Where(condition string) (d.DF, error) {
 
 // ***************** Helpers *****************
 
+// fileBatch is the # of rows read at a time when filtering a file.
+const fileBatch = 10000
+
+// fileDF creates a *DF from the vectors read from a file.
+func fileDF(names []string, memData []*d.Vector, opts ...d.DFopt) (*DF, error) {
+	var memDF *DF
+	for ind := range len(names) {
+		var (
+			col *Col
+			e   error
+		)
+		if col, e = NewCol(memData[ind], d.ColName(names[ind])); e != nil {
+			return nil, e
+		}
+
+		if ind == 0 {
+			if memDF, e = NewDFcol([]*Col{col}, opts...); e != nil {
+				return nil, e
+			}
+
+			continue
+		}
+
+		if ex := memDF.AppendColumn(col, false); ex != nil {
+			return nil, ex
+		}
+	}
+
+	memDF.row = -1
+
+	return memDF, nil
+}
+
+// stackColumn appends column cn of dfs into one *d.Vector, promoting int to float if the types differ.  The int
+// default value of f becomes its float default.
+func stackColumn(cn string, fileNames []string, dfs []*DF, f *d.Files) (*d.Vector, error) {
+	var (
+		vecs   []*d.Vector
+		dt     d.DataTypes
+		dtFile string // file that set dt
+	)
+	for ind, df := range dfs {
+		if len(df.ColumnNames()) != len(dfs[0].ColumnNames()) {
+			return nil, fmt.Errorf("%s has different columns than %s", fileNames[ind], fileNames[0])
+		}
+
+		var col d.Column
+		if col = df.Column(cn); col == nil {
+			return nil, fmt.Errorf("%s is missing column %s", fileNames[ind], cn)
+		}
+
+		v := col.Data()
+		switch vt := v.VectorType(); {
+		case ind == 0, vt == d.DTfloat && dt == d.DTint:
+			dt, dtFile = vt, fileNames[ind]
+		case vt == dt, vt == d.DTint && dt == d.DTfloat:
+		default:
+			return nil, fmt.Errorf("column %s is %v in %s but %v in %s", cn, vt, fileNames[ind], dt, dtFile)
+		}
+
+		vecs = append(vecs, v)
+	}
+
+	out := d.MakeVector(dt, 0)
+	for _, v := range vecs {
+		if v.VectorType() != dt {
+			var (
+				x   []int
+				e   error
+				flt []float64
+			)
+			if x, e = v.AsInt(); e != nil {
+				return nil, e
+			}
+
+			naInt, naFloat := f.DefaultValue(d.DTint).(int), f.DefaultValue(d.DTfloat).(float64)
+			for _, xi := range x {
+				if xi == naInt {
+					flt = append(flt, naFloat)
+					continue
+				}
+
+				flt = append(flt, float64(xi))
+			}
+
+			if v, e = d.NewVector(flt, d.DTfloat); e != nil {
+				return nil, e
+			}
+		}
+
+		if e := out.AppendVector(v); e != nil {
+			return nil, e
+		}
+	}
+
+	return out, nil
+}
+
+// fileFilter loads the rows of f that satisfy its filter, stopping once MaxRows rows are kept.
+func fileFilter(f *d.Files, opts ...d.DFopt) (*DF, error) {
+	defer func() { _ = f.Close() }()
+
+	var (
+		kept []*d.Vector
+		ex   *d.Expr
+	)
+	for _, dt := range f.ColumnTypes() {
+		kept = append(kept, d.MakeVector(dt, 0))
+	}
+
+	for f.MaxRows() == 0 || kept[0].Len() < f.MaxRows() {
+		var (
+			memData []*d.Vector
+			e       error
+		)
+		if memData, e = f.LoadRows(fileBatch); e != nil {
+			if e == io.EOF {
+				break
+			}
+
+			return nil, e
+		}
+
+		var batch *DF
+		if batch, e = fileDF(f.ColumnNames(), memData, opts...); e != nil {
+			return nil, e
+		}
+
+		if ex == nil {
+			if ex, e = d.Compile("filter:="+f.Filter(), batch.Fns()); e != nil {
+				return nil, e
+			}
+		}
+
+		var indicator d.Column
+		if indicator, e = ex.Value(batch); e != nil {
+			return nil, e
+		}
+
+		if indicator.DataType() != d.DTint {
+			return nil, fmt.Errorf("file filter must be int")
+		}
+
+		ind := indicator.Data()
+		if ind.Len() == 1 && batch.RowCount() > 1 {
+			ind = d.MakeVector(d.DTint, batch.RowCount())
+			for row := range ind.Len() {
+				ind.SetAny(indicator.Data().Element(0), row)
+			}
+		}
+
+		// don't keep more than MaxRows
+		if room := f.MaxRows() - kept[0].Len(); f.MaxRows() > 0 {
+			ind = ind.Copy()
+			for row := range ind.Len() {
+				if x := ind.Element(row).(int); x > 0 {
+					if room == 0 {
+						ind.SetAny(0, row)
+						continue
+					}
+
+					room--
+				}
+			}
+		}
+
+		for c, v := range memData {
+			if e1 := kept[c].AppendVector(v.Where(ind)); e1 != nil {
+				return nil, e1
+			}
+		}
+	}
+
+	return fileDF(f.ColumnNames(), kept, opts...)
+}
+
 func checkType(cols ...d.Column) error {
 	for _, c := range cols {
 		if _, ok := c.(*Col); !ok {
@@ -1093,8 +1443,14 @@ func buildGroups(df *DF, gbCol []*Col) (groups, error) {
 	// h will be the hash of the bytes of the index numbers for each level of the table columns
 	h := fnv.New64()
 
+	ctx := df.Context()
+
 	// scan the rows to build the table
 	for rowNum := range gbCol[0].Len() {
+		if rowNum%ctxRows == 0 && ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
 		// str is the byte array that is hashed, its length is 8 times the # of columns
 		var str []byte
 
@@ -1173,7 +1529,7 @@ func buildGroups(df *DF, gbCol []*Col) (groups, error) {
 			dfg *DF
 			e2  error
 		)
-		if dfg, e2 = NewDFcol(cols); e2 != nil {
+		if dfg, e2 = NewDFcol(cols, d.DFcontext(ctx)); e2 != nil {
 			return nil, e2
 		}
 
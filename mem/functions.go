@@ -90,7 +90,7 @@ func rawFuncs() []any {
 func varying(spec *d.FnSpec) d.Fn {
 	fn := func(info bool, df d.DF, inputs ...d.Column) *d.FnReturn {
 		if info {
-			return &d.FnReturn{Name: spec.Name, Inputs: nil,
+			return &d.FnReturn{Name: spec.Name, Inputs: spec.Inputs,
 				Output:  spec.Outputs,
 				Varying: true}
 		}
@@ -116,7 +116,7 @@ func varying(spec *d.FnSpec) d.Fn {
 		if spec.Inputs != nil {
 			ind = signature(spec.Inputs, inputs)
 			if ind < 0 {
-				panic(fmt.Errorf("no signature"))
+				return &d.FnReturn{Err: fmt.Errorf("no signature of %s matches its inputs", spec.Name)}
 			}
 
 			fnUse = fnToUse(spec.Fns, spec.Inputs[ind], spec.Outputs[ind])
@@ -164,7 +164,7 @@ func buildFn(spec *d.FnSpec) d.Fn {
 		if spec.Inputs != nil {
 			ind = signature(spec.Inputs, inputs)
 			if ind < 0 {
-				panic(fmt.Errorf("no signature"))
+				return &d.FnReturn{Err: fmt.Errorf("no signature of %s matches its inputs", spec.Name)}
 			}
 
 			fnUse = fnToUse(spec.Fns, spec.Inputs[ind], spec.Outputs[ind])
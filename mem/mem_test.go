@@ -1,19 +1,754 @@
 package mem
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
 	"database/sql"
 	"fmt"
+	"io"
+	"math"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/ClickHouse/clickhouse-go/v2"
 	d "github.com/invertedv/df"
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/encoding/unicode"
 )
 
+// TestCompile evaluates one compiled expression over several dataframes.
+func TestCompile(t *testing.T) {
+	ex, e := d.Compile("z := 2 * seq + 1", StandardFunctions())
+	assert.Nil(t, e)
+	assert.Equal(t, "z", ex.Name())
+
+	for _, n := range []int{3, 5} {
+		df, e0 := NewDFseq(n, "seq")
+		assert.Nil(t, e0)
+
+		e1 := ex.Eval(df)
+		assert.Nil(t, e1)
+		assert.Equal(t, d.DTint, ex.DataType())
+		assert.Equal(t, n, df.Column("z").Len())
+		assert.Equal(t, 2*n-1, df.Column("z").Data().Element(n-1))
+	}
+
+	// the type follows from the signatures and constants, without a DF
+	for expr, dt := range map[string]d.DataTypes{
+		"z := 2.0 * 3.5":        d.DTfloat,
+		"z := float(seq) + 1.0": d.DTfloat,
+		"z := seq + 1":          d.DTint, // only int + int fits
+		"z := seq > 2":          d.DTint,
+		"z := 'a'":              d.DTstring,
+		"z := seq + seq":        d.DTunknown, // seq may be int or float
+	} {
+		ex1, e1 := d.Compile(expr, StandardFunctions())
+		assert.Nil(t, e1)
+		assert.Equal(t, dt, ex1.DataType(), expr)
+	}
+
+	ex, e = d.Compile("z := seq * @n", StandardFunctions())
+	assert.Nil(t, e)
+	assert.Equal(t, d.DTunknown, ex.DataType())
+	assert.Equal(t, d.DTfloat, ex.Bind(map[string]any{"n": 1.5}).DataType())
+
+	_, e = d.Compile("z := notAFunction(seq)", StandardFunctions())
+	assert.NotNil(t, e)
+
+	_, e = d.Compile("z := abs(seq, seq)", StandardFunctions())
+	assert.NotNil(t, e)
+}
+
+// TestCheck type-checks expressions without evaluating them.
+func TestCheck(t *testing.T) {
+	df, e := NewDFseq(5, "seq")
+	assert.Nil(t, e)
+	assert.Nil(t, d.Parse(df, "x := float(seq)"))
+
+	info, e1 := d.Check(df, "y := if(seq > 2, x / 2.0, mean(x))")
+	assert.Nil(t, e1)
+	assert.Equal(t, "y", info.Name)
+	assert.Equal(t, d.DTfloat, info.DataType)
+	assert.ElementsMatch(t, []string{"seq", "x"}, info.Dependencies)
+	assert.False(t, info.IsScalar)
+	assert.Equal(t, "", info.SQL)
+	assert.Nil(t, df.Column("y"))
+
+	info, e1 = d.Check(df, "s := sum(seq)")
+	assert.Nil(t, e1)
+	assert.Equal(t, d.DTint, info.DataType)
+	assert.True(t, info.IsScalar)
+
+	info, e1 = d.Check(df, "c := colSum(x, x, 1.0)")
+	assert.Nil(t, e1)
+	assert.Equal(t, d.DTfloat, info.DataType)
+
+	// int + float is not allowed
+	_, e1 = d.Check(df, "y := seq + x")
+	assert.NotNil(t, e1)
+}
+
 // Examples
 
+// TestParseError checks that parse errors locate the problem and suggest fixes.
+func TestParseError(t *testing.T) {
+	df, e := NewDFseq(5, "seq")
+	assert.Nil(t, e)
+	assert.Nil(t, d.Parse(df, "x := float(seq)"))
+
+	var pe *d.ParseError
+	e = d.Parse(df, "y := abss(seq) + 1")
+	assert.ErrorAs(t, e, &pe)
+	assert.Equal(t, 5, pe.Pos)
+	assert.Equal(t, "abss", pe.Token)
+	assert.Contains(t, pe.Suggestions, "abs")
+
+	e = d.Parse(df, "y := 2 * sqe")
+	assert.ErrorAs(t, e, &pe)
+	assert.Equal(t, 9, pe.Pos)
+	assert.Equal(t, []string{"seq"}, pe.Suggestions)
+
+	e = d.Parse(df, "y := exp(x, x)")
+	assert.ErrorAs(t, e, &pe)
+	assert.Equal(t, 5, pe.Pos)
+	assert.Contains(t, pe.Expected, "exp(float)")
+
+	e = d.Parse(df, "y := x + 'a'")
+	assert.ErrorAs(t, e, &pe)
+	assert.Equal(t, 7, pe.Pos)
+	assert.NotEmpty(t, pe.Expected)
+
+	e = d.Parse(df, "y := (x + 1))")
+	assert.ErrorAs(t, e, &pe)
+	assert.Equal(t, 12, pe.Pos)
+
+	// a repeated piece of the expression is located where it failed
+	e = d.Parse(df, "y := exp(x) + exp(x, x)")
+	assert.ErrorAs(t, e, &pe)
+	assert.Equal(t, 14, pe.Pos)
+
+	e = d.Parse(df, "y := float(seq) + eq")
+	assert.ErrorAs(t, e, &pe)
+	assert.Equal(t, 18, pe.Pos)
+
+	e = d.Parse(df, "y := (x + 1.0) * (x + 'a')")
+	assert.ErrorAs(t, e, &pe)
+	assert.Equal(t, 20, pe.Pos)
+
+	e = d.Parse(df, "y := 1 - -'a'")
+	assert.ErrorAs(t, e, &pe)
+	assert.Equal(t, 9, pe.Pos)
+	assert.Equal(t, "-", pe.Token)
+
+	_, e = d.RunDFfn(df.Fns().Get("exp"), df, []d.Column{df.Column("x"), df.Column("x")})
+	assert.ErrorAs(t, e, &pe)
+	assert.Equal(t, -1, pe.Pos)
+	assert.NotEmpty(t, pe.Expected)
+}
+
+// TestParams binds Go values to parameters in expressions.
+func TestParams(t *testing.T) {
+	cutoff := time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)
+	params := map[string]any{"rate": float32(0.5), "n": int64(2), "name": "it's", "cutoff": cutoff}
+
+	df, e := NewDFseq(5, "seq")
+	assert.Nil(t, e)
+
+	assert.Nil(t, d.ParseParams(df, "x := float(seq) * @rate", params))
+	assert.Equal(t, 2.0, df.Column("x").Data().Element(4))
+
+	assert.Nil(t, d.ParseParams(df, "s := concat(@name, 'x')", params))
+	assert.Equal(t, "it'sx", df.Column("s").Data().Element(0))
+
+	assert.Nil(t, d.Parse(df, "dt := addMonths(date('20240101'), seq)"))
+	assert.Nil(t, d.ParseParams(df, "c := dt > $cutoff", params))
+	assert.Equal(t, []int{0, 1, 1, 1, 1}, df.Column("c").Data().AsAny())
+
+	dfw, e1 := df.WhereParams("seq >= @n", params)
+	assert.Nil(t, e1)
+	assert.Equal(t, 3, dfw.RowCount())
+
+	dfb, e2 := df.ByParams("c", params, "m := sum(seq) + @n")
+	assert.Nil(t, e2)
+	assert.Equal(t, 2, dfb.RowCount())
+
+	// parameters don't stay with the DF
+	_, e = df.Where("seq >= @n")
+	assert.NotNil(t, e)
+
+	info, e5 := d.CheckParams(df, "y := seq * @n", params)
+	assert.Nil(t, e5)
+	assert.Equal(t, d.DTint, info.DataType)
+
+	ex, e3 := d.Compile("y := seq * @n", df.Fns())
+	assert.Nil(t, e3)
+	v, e4 := ex.Bind(map[string]any{"n": 3}).Value(df)
+	assert.Nil(t, e4)
+	assert.Equal(t, 12, v.Data().Element(4))
+
+	var pe *d.ParseError
+	e = d.ParseParams(df, "y := seq * @nn", params)
+	assert.ErrorAs(t, e, &pe)
+	assert.Equal(t, []string{"n"}, pe.Suggestions)
+}
+
+// TestContext checks that a cancelled context stops By and Join.
+func TestContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	df, e := NewDFseq(10, "seq", d.DFcontext(ctx))
+	assert.Nil(t, e)
+	assert.Nil(t, d.Parse(df, "g := seq - 2 * int(float(seq) / 2.0)"))
+
+	_, e = df.By("g", "n := count(seq)")
+	assert.Nil(t, e)
+
+	cancel()
+	assert.Equal(t, ctx, df.Copy().Context())
+
+	_, e = df.By("g", "n := count(seq)")
+	assert.ErrorIs(t, e, context.Canceled)
+
+	_, e = df.Join(df.Copy(), "seq")
+	assert.ErrorIs(t, e, context.Canceled)
+}
+
+// TestFilesRFC4180 round-trips free-text values through Files and reads a hand-written RFC 4180 file.
+func TestFilesRFC4180(t *testing.T) {
+	txt := []string{`plain`, `a,b`, `say "hi"`, "two\nlines", "crlf\r\nline", `""`, ` padded `, `"`}
+	n := []int{1, 2, 3, 4, 5, 6, 7, 8}
+	c1, e := NewCol(txt, d.ColName("txt"))
+	assert.Nil(t, e)
+	c2, e := NewCol(n, d.ColName("n"))
+	assert.Nil(t, e)
+	df, e := NewDFcol([]*Col{c1, c2})
+	assert.Nil(t, e)
+
+	fileName := t.TempDir() + "/rfc.csv"
+	for _, crlf := range []bool{false, true} {
+		fs, _ := d.NewFiles(d.FileCRLF(crlf))
+		assert.Nil(t, fs.Save(fileName, df))
+
+		f, _ := d.NewFiles(d.FileStrict(true))
+		assert.Nil(t, f.Open(fileName))
+		assert.Equal(t, []d.DataTypes{d.DTstring, d.DTint}, f.FieldTypes())
+		dfy, e1 := FileLoad(f)
+		assert.Nil(t, e1)
+		assert.Equal(t, txt, dfy.Column("txt").Data().AsAny())
+		assert.Equal(t, n, dfy.Column("n").Data().AsAny())
+	}
+
+	raw := "\"k\",note,z\r\n" +
+		"1,\"He said \"\"no\"\"\r\nthen left\",\"20221231\"\r\n" +
+		"2,,\"20230101\"\r\n"
+	assert.Nil(t, os.WriteFile(fileName, []byte(raw), 0o644))
+	f, _ := d.NewFiles(d.FileStrict(true))
+	assert.Nil(t, f.Open(fileName))
+	assert.Equal(t, []string{"k", "note", "z"}, f.FieldNames())
+	assert.Equal(t, []d.DataTypes{d.DTint, d.DTstring, d.DTstring}, f.FieldTypes())
+	dfy, e := FileLoad(f)
+	assert.Nil(t, e)
+	assert.Equal(t, []string{"He said \"no\"\r\nthen left", ""}, dfy.Column("note").Data().AsAny())
+	assert.Equal(t, []string{"20221231", "20230101"}, dfy.Column("z").Data().AsAny())
+
+	assert.Nil(t, os.WriteFile(fileName, []byte("k,x\n1,\"open\n"), 0o644))
+	f, _ = d.NewFiles()
+	assert.NotNil(t, f.Open(fileName))
+}
+
+// TestFilesReader loads from non-seekable readers and saves to a writer.
+func TestFilesReader(t *testing.T) {
+	load := func(r io.Reader, opts ...d.FileOpt) *DF {
+		f, _ := d.NewFiles(opts...)
+		assert.Nil(t, f.OpenReader(r))
+		df, e := FileLoad(f)
+		assert.Nil(t, e)
+		return df
+	}
+
+	f, _ := d.NewFiles()
+	assert.Nil(t, f.Open(os.Getenv("datapath")+"d1.csv"))
+	exp, e := FileLoad(f)
+	assert.Nil(t, e)
+
+	raw, e := os.ReadFile(os.Getenv("datapath") + "d1.csv")
+	assert.Nil(t, e)
+	// a pipe can't seek, so detect must replay the rows it examined
+	for _, peek := range []int{0, 2} {
+		pr, pw := io.Pipe()
+		go func() { _, _ = pw.Write(raw); _ = pw.Close() }()
+		df := load(pr, d.FilePeek(peek))
+		for _, cn := range exp.ColumnNames() {
+			assert.Equal(t, exp.Column(cn).Data(), df.Column(cn).Data())
+		}
+	}
+
+	var buf bytes.Buffer
+	fs, _ := d.NewFiles()
+	assert.Nil(t, fs.SaveWriter(&buf, exp))
+	df := load(&buf)
+	for _, cn := range exp.ColumnNames() {
+		assert.Equal(t, exp.Column(cn).Data(), df.Column(cn).Data())
+	}
+
+	// fixed-width, last line without an EOL
+	fw, e := os.ReadFile(os.Getenv("datapath") + "testFW2.txt")
+	assert.Nil(t, e)
+	df = load(bytes.NewReader(bytes.TrimRight(fw, "\n")), d.FileFieldWidths([]int{1, 5, 2, 3, 10, 8}))
+	for _, cn := range []string{"k", "x", "y", "yy", "z", "dt"} {
+		assert.Equal(t, exp.Column(cn).Data(), df.Column(cn).Data())
+	}
+
+	// with the default peek, a reader is examined only in part but a file is examined in full
+	long := "x\n" + strings.Repeat("1\n", 10001) + strings.Repeat("abc\n", 20000)
+	fr, _ := d.NewFiles()
+	assert.Nil(t, fr.OpenReader(strings.NewReader(long)))
+	assert.Equal(t, []d.DataTypes{d.DTint}, fr.FieldTypes())
+
+	fileName := t.TempDir() + "/long.csv"
+	assert.Nil(t, os.WriteFile(fileName, []byte(long), 0o644))
+	fr, _ = d.NewFiles()
+	assert.Nil(t, fr.Open(fileName))
+	assert.Equal(t, []d.DataTypes{d.DTstring}, fr.FieldTypes())
+}
+
+// TestFilesCompressed saves and reads gzip and zstd files, chosen by extension or option and detected on read.
+func TestFilesCompressed(t *testing.T) {
+	f, _ := d.NewFiles()
+	assert.Nil(t, f.Open(os.Getenv("datapath")+"d1.csv"))
+	exp, e := FileLoad(f)
+	assert.Nil(t, e)
+
+	same := func(df *DF, cols ...string) {
+		for _, cn := range cols {
+			assert.Equal(t, exp.Column(cn).Data(), df.Column(cn).Data())
+		}
+	}
+
+	dir := t.TempDir()
+	for ext, magic := range map[string][]byte{".csv.gz": {0x1f, 0x8b}, ".txt.zst": {0x28, 0xb5, 0x2f, 0xfd}} {
+		fileName := dir + "/d1" + ext
+		fs, _ := d.NewFiles()
+		assert.Nil(t, fs.Save(fileName, exp))
+		raw, _ := os.ReadFile(fileName)
+		assert.True(t, bytes.HasPrefix(raw, magic))
+
+		fr, _ := d.NewFiles()
+		assert.Nil(t, fr.Open(fileName))
+		df, e1 := FileLoad(fr)
+		assert.Nil(t, e1)
+		same(df, exp.ColumnNames()...)
+	}
+
+	var buf bytes.Buffer
+	fs, _ := d.NewFiles(d.FileCompression(d.CompressZstd))
+	assert.Nil(t, fs.SaveWriter(&buf, exp))
+	fr, _ := d.NewFiles()
+	assert.Nil(t, fr.OpenReader(&buf))
+	df, e := FileLoad(fr)
+	assert.Nil(t, e)
+	same(df, exp.ColumnNames()...)
+
+	// fixed-width
+	fw, _ := os.ReadFile(os.Getenv("datapath") + "testFW2.txt")
+	buf.Reset()
+	zw := gzip.NewWriter(&buf)
+	_, _ = zw.Write(fw)
+	_ = zw.Close()
+	fr, _ = d.NewFiles(d.FileFieldWidths([]int{1, 5, 2, 3, 10, 8}))
+	assert.Nil(t, fr.OpenReader(&buf))
+	df, e = FileLoad(fr)
+	assert.Nil(t, e)
+	same(df, "k", "x", "y", "yy", "z", "dt")
+
+	_, e = d.NewFiles(d.FileCompression("lzma"))
+	assert.NotNil(t, e)
+}
+
+// TestFilesSubset loads selected columns and rows of a file.
+func TestFilesSubset(t *testing.T) {
+	load := func(opts ...d.FileOpt) (*DF, error) {
+		f, _ := d.NewFiles(opts...)
+		if e := f.Open(os.Getenv("datapath") + "d1.csv"); e != nil {
+			return nil, e
+		}
+
+		return FileLoad(f)
+	}
+
+	df, e := load(d.FileColumns("z", "k"))
+	assert.Nil(t, e)
+	assert.Equal(t, []string{"z", "k"}, df.ColumnNames())
+	assert.Equal(t, []int{1, 2, 3, 4, 5, 6}, df.Column("k").Data().AsAny())
+
+	df, e = load(d.FileSkipRows(2), d.FileMaxRows(3))
+	assert.Nil(t, e)
+	assert.Equal(t, []int{3, 4, 5}, df.Column("k").Data().AsAny())
+
+	df, e = load(d.FileColumns("k", "x"), d.FileFilter("x > 0.0"), d.FileSkipRows(1), d.FileMaxRows(3))
+	assert.Nil(t, e)
+	assert.Equal(t, []int{3, 5, 6}, df.Column("k").Data().AsAny())
+	assert.Equal(t, []float64{3, 2, 3.5}, df.Column("x").Data().AsAny())
+
+	df, e = load(d.FileFilter("k > 100"))
+	assert.Nil(t, e)
+	assert.Equal(t, 0, df.RowCount())
+
+	// compare to Where on the full file
+	f, _ := d.NewFiles(d.FileFilter("age >= 10"), d.FileColumns("acct", "age"))
+	assert.Nil(t, f.Open(os.Getenv("datapath")+"getting_started.csv"))
+	dff, e := FileLoad(f)
+	assert.Nil(t, e)
+	f, _ = d.NewFiles()
+	assert.Nil(t, f.Open(os.Getenv("datapath")+"getting_started.csv"))
+	all, e := FileLoad(f)
+	assert.Nil(t, e)
+	exp, e := all.Where("age >= 10")
+	assert.Nil(t, e)
+	assert.Equal(t, exp.Column("acct").Data(), dff.Column("acct").Data())
+
+	_, e = load(d.FileColumns("k", "nope"))
+	assert.NotNil(t, e)
+	_, e = load(d.FileFilter("k + "))
+	assert.NotNil(t, e)
+}
+
+// TestFilesParallel checks the parallel loader matches the serial loader.
+func TestFilesParallel(t *testing.T) {
+	load := func(fileName string, opts ...d.FileOpt) (*DF, error) {
+		f, _ := d.NewFiles(opts...)
+		if e := f.Open(fileName); e != nil {
+			return nil, e
+		}
+
+		return FileLoad(f)
+	}
+
+	// free text with separators, quotes and line breaks that fall on chunk boundaries
+	var sb strings.Builder
+	sb.WriteString("k,note,x\n")
+	for ind := range 500 {
+		fmt.Fprintf(&sb, "%d,\"row %d, \"\"quoted\"\"\nsecond line\",%d.5\r\n", ind, ind, ind)
+	}
+
+	free := t.TempDir() + "/free.csv"
+	assert.Nil(t, os.WriteFile(free, []byte(sb.String()), 0o644))
+	gs := os.Getenv("datapath") + "getting_started.csv"
+
+	for _, fileName := range []string{gs, free} {
+		for _, opts := range [][]d.FileOpt{nil, {d.FilePeek(10)}, {d.FileSkipRows(37), d.FileMaxRows(250)}, {d.FileMaxRows(1)}} {
+			exp, e := load(fileName, opts...)
+			assert.Nil(t, e)
+			for _, chunk := range []int{1, 100, 1000, 1 << 20} {
+				df, e1 := load(fileName, append(opts, d.FileWorkers(4), d.FileChunkSize(chunk))...)
+				assert.Nil(t, e1)
+				for _, cn := range exp.ColumnNames() {
+					assert.Equal(t, exp.Column(cn).Data(), df.Column(cn).Data())
+				}
+			}
+		}
+	}
+
+	// the same error as the serial loader
+	bad := t.TempDir() + "/bad.csv"
+	assert.Nil(t, os.WriteFile(bad, []byte(strings.Replace(sb.String(), "400.5", "x", 1)), 0o644))
+	_, e := load(bad, d.FileStrict(true), d.FilePeek(5))
+	assert.NotNil(t, e)
+	_, e1 := load(bad, d.FileStrict(true), d.FilePeek(5), d.FileWorkers(3), d.FileChunkSize(500))
+	assert.Equal(t, e, e1)
+
+	// a malformed record among the skipped rows fails both loaders
+	short := t.TempDir() + "/short.csv"
+	assert.Nil(t, os.WriteFile(short, []byte(strings.Replace(sb.String(), ",20.5\r\n", "\r\n", 1)), 0o644))
+	_, e = load(short, d.FilePeek(5), d.FileSkipRows(50))
+	assert.NotNil(t, e)
+	_, e1 = load(short, d.FilePeek(5), d.FileSkipRows(50), d.FileWorkers(3), d.FileChunkSize(500))
+	assert.Equal(t, e, e1)
+}
+
+// TestFilesFixedWidth saves a fixed-width file with a schema sidecar and reopens it from the sidecar.
+func TestFilesFixedWidth(t *testing.T) {
+	f, _ := d.NewFiles()
+	assert.Nil(t, f.Open(os.Getenv("datapath")+"d1.csv"))
+	exp, e := FileLoad(f)
+	assert.Nil(t, e)
+
+	dir := t.TempDir()
+	fileName := dir + "/d1.txt"
+	widths := []int{2, 6, 3, 4, 10, 9, 6}
+	fs, _ := d.NewFiles(d.FileFieldWidths(widths))
+	assert.Nil(t, fs.Save(fileName, exp))
+	raw, _ := os.ReadFile(fileName)
+	lines := strings.Split(string(raw), "\n")
+	assert.Equal(t, "k x     y  yy  z         dt       R     ", lines[0])
+	assert.Equal(t, " 1  1.00  1   120221231  20221231   2.20", lines[1])
+
+	for _, sidecar := range []string{dir + "/d1.json", dir + "/d1.yaml"} {
+		assert.Nil(t, fs.SaveSchema(sidecar))
+		fr, e1 := d.NewFiles(d.FileUseSchema(sidecar))
+		assert.Nil(t, e1)
+		assert.Equal(t, widths, fr.FieldWidths())
+		assert.Nil(t, fr.Open(fileName))
+		df, e2 := FileLoad(fr)
+		assert.Nil(t, e2)
+		for _, cn := range exp.ColumnNames() {
+			assert.Equal(t, exp.Column(cn).Data(), df.Column(cn).Data())
+		}
+	}
+
+	// a schema for a separated file
+	fs, _ = d.NewFiles(d.FileSep('|'))
+	assert.Nil(t, fs.Save(dir+"/d1.psv", exp))
+	assert.Nil(t, fs.SaveSchema(dir+"/psv.json"))
+	fr, _ := d.NewFiles(d.FileUseSchema(dir + "/psv.json"))
+	assert.Nil(t, fr.Open(dir+"/d1.psv"))
+	df, e := FileLoad(fr)
+	assert.Nil(t, e)
+	assert.Equal(t, exp.Column("z").Data(), df.Column("z").Data())
+
+	// strings are truncated, numbers are not
+	c1, _ := NewCol([]string{"abcdefgh", "ab"}, d.ColName("s"))
+	c2, _ := NewCol([]int{1, 12345}, d.ColName("n"))
+	dfs, _ := NewDFcol([]*Col{c1, c2})
+	fs, _ = d.NewFiles(d.FileFieldWidths([]int{4, 5}), d.FileHeader(false))
+	assert.Nil(t, fs.Save(fileName, dfs))
+	raw, _ = os.ReadFile(fileName)
+	assert.Equal(t, "abcd    1\nab  12345\n", string(raw))
+	fs, _ = d.NewFiles(d.FileFieldWidths([]int{4, 4}))
+	assert.NotNil(t, fs.Save(fileName, dfs))
+
+	_, e = d.NewFiles(d.FileUseSchema(dir + "/none.json"))
+	assert.NotNil(t, e)
+}
+
+// TestFilesFormats reads and writes with per-column formats, NA tokens and European numbers.
+func TestFilesFormats(t *testing.T) {
+	dir := t.TempDir()
+	raw := "id;amt;dt;note\n1;1.234,5;31.12.2022;NA\n2;.;01.01.2023;x\n3;12,25;NA;y\n"
+	assert.Nil(t, os.WriteFile(dir+"/eu.csv", []byte(raw), 0o644))
+	opts := []d.FileOpt{d.FileSep(';'), d.FileThousandsSep('.'), d.FileDecimalComma(true), d.FileNA("NA", "."),
+		d.FileColumnDateFormat("dt", "02.01.2006"), d.FileDefaultFloat(-1), d.FileStrict(true)}
+	f, e := d.NewFiles(opts...)
+	assert.Nil(t, e)
+	assert.Nil(t, f.Open(dir+"/eu.csv"))
+	assert.Equal(t, []d.DataTypes{d.DTint, d.DTfloat, d.DTdate, d.DTstring}, f.FieldTypes())
+	df, e := FileLoad(f)
+	assert.Nil(t, e)
+	assert.Equal(t, []float64{1234.5, -1, 12.25}, df.Column("amt").Data().AsAny())
+	assert.Equal(t, []time.Time{time.Date(2022, 12, 31, 0, 0, 0, 0, time.UTC), time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(1960, 1, 1, 0, 0, 0, 0, time.UTC)}, df.Column("dt").Data().AsAny())
+	assert.Equal(t, []string{"", "x", "y"}, df.Column("note").Data().AsAny())
+
+	fs, _ := d.NewFiles(d.FileSep(';'), d.FileDecimalComma(true), d.FileColumnFloatFormat("amt", "%.3f"),
+		d.FileColumnDateFormat("dt", "2006/01/02"))
+	assert.Nil(t, fs.Save(dir+"/out.csv", df))
+	out, _ := os.ReadFile(dir + "/out.csv")
+	assert.Equal(t, "id;amt;dt;note", strings.Split(string(out), "\n")[0])
+	assert.Equal(t, `1;1234,500;2022/12/31;""`, strings.Split(string(out), "\n")[1])
+
+	// the schema records the layouts, so the file reopens without them
+	assert.Nil(t, fs.SaveSchema(dir+"/out.json"))
+	f, _ = d.NewFiles(d.FileUseSchema(dir+"/out.json"), d.FileDecimalComma(true))
+	assert.Nil(t, f.Open(dir+"/out.csv"))
+	dfy, e := FileLoad(f)
+	assert.Nil(t, e)
+	for _, cn := range df.ColumnNames() {
+		assert.Equal(t, df.Column(cn).Data(), dfy.Column(cn).Data())
+	}
+
+	// quoted numbers with separators
+	assert.Nil(t, os.WriteFile(dir+"/q.csv", []byte("k,amt\n1,\"1,234.5\"\n2,\"7\"\n"), 0o644))
+	f, _ = d.NewFiles(d.FileThousandsSep(','))
+	assert.Nil(t, f.Open(dir+"/q.csv"))
+	dfy, e = FileLoad(f)
+	assert.Nil(t, e)
+	assert.Equal(t, []float64{1234.5, 7}, dfy.Column("amt").Data().AsAny())
+
+	_, e = d.NewFiles(d.FileColumnDateFormat("dt", "no date"))
+	assert.NotNil(t, e)
+	_, e = d.NewFiles(d.FileColumnFloatFormat("amt", "%d"))
+	assert.NotNil(t, e)
+}
+
+// TestFilesSniff infers the separator, header and encoding.
+func TestFilesSniff(t *testing.T) {
+	dir := t.TempDir()
+	load := func(raw []byte, opts ...d.FileOpt) *DF {
+		assert.Nil(t, os.WriteFile(dir+"/sniff.txt", raw, 0o644))
+		f, _ := d.NewFiles(append(opts, d.FileSniff(true))...)
+		assert.Nil(t, f.Open(dir+"/sniff.txt"))
+		df, e := FileLoad(f)
+		assert.Nil(t, e)
+		return df
+	}
+
+	f, _ := d.NewFiles()
+	assert.Nil(t, f.Open(os.Getenv("datapath")+"d1.csv"))
+	exp, e := FileLoad(f)
+	assert.Nil(t, e)
+	raw, _ := os.ReadFile(os.Getenv("datapath") + "d1.csv")
+
+	// tab-separated with a header
+	df := load(bytes.ReplaceAll(raw, []byte(","), []byte("\t")))
+	assert.Equal(t, exp.ColumnNames(), df.ColumnNames())
+	for _, cn := range exp.ColumnNames() {
+		assert.Equal(t, exp.Column(cn).Data(), df.Column(cn).Data())
+	}
+
+	// pipe-separated without a header
+	body := raw[bytes.IndexByte(raw, '\n')+1:]
+	df = load(bytes.ReplaceAll(body, []byte(","), []byte("|")))
+	assert.Equal(t, []string{"col1", "col2", "col3", "col4", "col5", "col6", "col7"}, df.ColumnNames())
+	assert.Equal(t, exp.Column("k").Data(), df.Column("col1").Data())
+	assert.Equal(t, exp.Column("R").Data(), df.Column("col7").Data())
+
+	// the names are given, so the header is skipped
+	df = load(bytes.ReplaceAll(raw, []byte(","), []byte(";")), d.FileFieldNames([]string{"a", "b", "c", "d", "e", "f", "g"}))
+	assert.Equal(t, exp.Column("k").Data(), df.Column("a").Data())
+
+	// Windows-1252
+	df = load([]byte("name;amt\nJos\xe9;1,5\nZo\xeb;2,5\n"))
+	assert.Equal(t, []string{"José", "Zoë"}, df.Column("name").Data().AsAny())
+
+	// UTF-16 with a BOM
+	u16, _ := unicode.UTF16(unicode.LittleEndian, unicode.UseBOM).NewEncoder().Bytes([]byte("a,b\n1,x\n2,y\n"))
+	df = load(u16)
+	assert.Equal(t, []int{1, 2}, df.Column("a").Data().AsAny())
+	assert.Equal(t, []string{"x", "y"}, df.Column("b").Data().AsAny())
+
+	// strings only: the first row repeats in the data, so it isn't a header
+	df = load([]byte("apple,pear\napple,plum\n"))
+	assert.Equal(t, []string{"apple", "apple"}, df.Column("col1").Data().AsAny())
+
+	// an explicit encoding
+	assert.Nil(t, os.WriteFile(dir+"/latin.csv", []byte("name,n\nAndr\xe9,1\n"), 0o644))
+	f, _ = d.NewFiles(d.FileEncoding("Latin1"))
+	assert.Nil(t, f.Open(dir+"/latin.csv"))
+	df, e = FileLoad(f)
+	assert.Nil(t, e)
+	assert.Equal(t, []string{"André"}, df.Column("name").Data().AsAny())
+
+	_, e = d.NewFiles(d.FileEncoding("ebcdic"))
+	assert.NotNil(t, e)
+}
+
+func TestFilesRejects(t *testing.T) {
+	dir := t.TempDir()
+	raw := "n,x,note\n1,1.5,a\nabc,2.5,\"two\nlines\"\n3,?,c\n4,4.5,d\n5,x5,e\n"
+	assert.Nil(t, os.WriteFile(dir+"/bad.csv", []byte(raw), 0o644))
+	types := d.FileFieldTypes([]d.DataTypes{d.DTint, d.DTfloat, d.DTstring})
+
+	fn := dir + "/bad.csv"
+	expBad := []d.BadValue{
+		{File: fn, Line: 3, Column: "n", Raw: "abc", Type: d.DTint},
+		{File: fn, Line: 5, Column: "x", Raw: "?", Type: d.DTfloat},
+		{File: fn, Line: 7, Column: "x", Raw: "x5", Type: d.DTfloat},
+	}
+
+	for _, workers := range []int{1, 3} {
+		f, _ := d.NewFiles(types, d.FileStrict(true), d.FileRejects(dir+"/rejects.csv"),
+			d.FileWorkers(workers), d.FileChunkSize(8))
+		assert.Nil(t, f.Open(dir+"/bad.csv"))
+		df, e := FileLoad(f)
+		assert.Nil(t, e)
+		assert.Equal(t, 5, df.RowCount())
+		assert.Equal(t, []int{1, math.MaxInt, 3, 4, 5}, df.Column("n").Data().AsAny())
+		assert.Equal(t, expBad, f.BadValues())
+
+		errs, e := FileErrors(f)
+		assert.Nil(t, e)
+		assert.Equal(t, []int{3, 5, 7}, errs.Column("line").Data().AsAny())
+		assert.Equal(t, []string{"n", "x", "x"}, errs.Column("column").Data().AsAny())
+
+		rej, _ := os.ReadFile(dir + "/rejects.csv")
+		exp := "file,line,column,raw,type\n" + fn + ",3,n,\"abc\",DTint\n" + fn + ",5,x,\"?\",DTfloat\n" +
+			fn + ",7,x,\"x5\",DTfloat\n"
+		assert.Equal(t, exp, string(rej))
+
+		// the third bad value exceeds the limit
+		f, _ = d.NewFiles(types, d.FileReport(2), d.FileWorkers(workers), d.FileChunkSize(8))
+		assert.Nil(t, f.Open(dir+"/bad.csv"))
+		_, e = FileLoad(f)
+		assert.NotNil(t, e)
+	}
+
+	// no report
+	f, _ := d.NewFiles(types)
+	assert.Nil(t, f.Open(dir+"/bad.csv"))
+	_, e := FileLoad(f)
+	assert.Nil(t, e)
+	assert.Nil(t, f.BadValues())
+	_, e = FileErrors(f)
+	assert.NotNil(t, e)
+}
+
+func TestFilesLoad(t *testing.T) {
+	dir := t.TempDir()
+	for fn, raw := range map[string]string{
+		"m1.csv": "a,b\n1,x\n2,y\n",
+		"m2.csv": "b,a\nz,3.5\n",
+		"m3.csv": "a,b\n4,w\n",
+		"m4.txt": "c\n1\n",
+		"n1.csv": "a,b\n1,x\n,y\n",
+		"n2.csv": "a,b\n2.5,z\n",
+		"s1.csv": "a,b\nq,x\n",
+	} {
+		assert.Nil(t, os.WriteFile(dir+"/"+fn, []byte(raw), 0o644))
+	}
+
+	for _, n := range []int{1, 2} {
+		f, _ := d.NewFiles()
+		df, e := FilesLoad(dir+"/m*.csv", f, "src", n)
+		assert.Nil(t, e)
+		assert.Equal(t, []string{"a", "b", "src"}, df.ColumnNames())
+		assert.Equal(t, []float64{1, 2, 3.5, 4}, df.Column("a").Data().AsAny())
+		assert.Equal(t, []string{"x", "y", "z", "w"}, df.Column("b").Data().AsAny())
+		assert.Equal(t, []string{dir + "/m1.csv", dir + "/m1.csv", dir + "/m2.csv", dir + "/m3.csv"},
+			df.Column("src").Data().AsAny())
+	}
+
+	// without a source column, and the types agree
+	f, _ := d.NewFiles()
+	df, e := FilesLoad(dir+"/m[13].csv", f, "", 1)
+	assert.Nil(t, e)
+	assert.Equal(t, []int{1, 2, 4}, df.Column("a").Data().AsAny())
+
+	// a missing int becomes a missing float
+	f, _ = d.NewFiles()
+	df, e = FilesLoad(dir+"/n*.csv", f, "", 2)
+	assert.Nil(t, e)
+	assert.Equal(t, []float64{1, math.MaxFloat64, 2.5}, df.Column("a").Data().AsAny())
+
+	// column a is int in n1 but string in s1
+	f, _ = d.NewFiles()
+	_, e = FilesLoad(dir+"/[ns]1.csv", f, "", 1)
+	assert.NotNil(t, e)
+
+	// bad values are reported by file
+	f, _ = d.NewFiles(d.FileFieldTypes([]d.DataTypes{d.DTint, d.DTint}), d.FileReport(0))
+	_, e = FilesLoad(dir+"/m[13].csv", f, "", 1)
+	assert.Nil(t, e)
+	assert.Equal(t, []string{dir + "/m1.csv", dir + "/m1.csv", dir + "/m3.csv"},
+		[]string{f.BadValues()[0].File, f.BadValues()[1].File, f.BadValues()[2].File})
+
+	// different columns
+	f, _ = d.NewFiles()
+	_, e = FilesLoad(dir+"/m*", f, "", 1)
+	assert.NotNil(t, e)
+
+	_, e = FilesLoad(dir+"/none*.csv", f, "", 1)
+	assert.NotNil(t, e)
+
+	_, e = FilesLoad(dir+"/m*.csv", f, "a", 1)
+	assert.NotNil(t, e)
+
+	_, e = FilesLoad(dir+"/m*.csv", f, "", 0)
+	assert.NotNil(t, e)
+}
+
 func TestStart1(t *testing.T) {
 	var (
 		f  *d.Files
@@ -2,7 +2,10 @@ package df
 
 import (
 	"fmt"
+	"reflect"
+	"sort"
 	"strings"
+	"unicode"
 )
 
 // Parse parses the expression expr and appends the result to df.
@@ -12,59 +15,264 @@ import (
 //
 // A list of functions available is in the documentation.
 func Parse(df DF, expr string) error {
+	return ParseParams(df, expr, nil)
+}
+
+// ParseParams is Parse with values for the parameters in expr.  An expression refers to the parameter
+// "rate" as @rate or $rate.  The values become constants of the corresponding type.
+func ParseParams(df DF, expr string, params map[string]any) error {
+	var (
+		ex *Expr
+		e  error
+	)
+	if ex, e = Compile(expr, df.Fns()); e != nil {
+		return e
+	}
+
+	return ex.Bind(params).Eval(df)
+}
+
+// ExprInfo describes the result of an expression.  It is returned by Check.
+type ExprInfo struct {
+	Name         string    // name of the result column
+	DataType     DataTypes // data type of the result
+	Dependencies []string  // columns of the DF needed to calculate the result
+	IsScalar     bool      // true if the result is a single value (e.g. sum(x))
+
+	// SQL is the query that generates the result if df is a database DF.  It is empty if
+	// the expression makes a categorical column, since the levels depend on the data.
+	SQL string
+}
+
+// Check type-checks expr against the columns of df without evaluating it.  The function overloads
+// are resolved using the info mode of Fn.
+func Check(df DF, expr string) (*ExprInfo, error) {
+	return CheckParams(df, expr, nil)
+}
+
+// CheckParams is Check with values for the parameters in expr.
+func CheckParams(df DF, expr string, params map[string]any) (*ExprInfo, error) {
+	var (
+		ex *Expr
+		e  error
+	)
+	if ex, e = Compile(expr, df.Fns()); e != nil {
+		return nil, e
+	}
+
+	ex.Bind(params)
+
+	var (
+		dt       DataTypes
+		isScalar bool
+		e1       error
+	)
+	if dt, isScalar, e1 = ex.ot.check(df, params); e1 != nil {
+		return nil, ex.locate(e1)
+	}
+
+	info := &ExprInfo{
+		Name:         ex.Name(),
+		DataType:     dt,
+		Dependencies: ex.ot.dependencies,
+		IsScalar:     isScalar,
+	}
+
+	if _, ok := df.(HasMQdlct); !ok || ex.ot.categorical() {
+		return info, nil
+	}
+
+	// For database DFs, evaluating the expression only builds SQL.  Work on a copy so df is unchanged.
+	dfc := df.Copy()
+	if e2 := ex.Eval(dfc); e2 != nil {
+		return nil, e2
+	}
+
+	var e3 error
+	if info.SQL, e3 = dfc.(HasMQdlct).MakeQueryE(ex.Name()); e3 != nil {
+		return nil, e3
+	}
+
+	return info, nil
+}
+
+// Expr is a compiled expression.  Compile builds the parse tree and resolves the functions once so that
+// the expression can be evaluated repeatedly (e.g. over the groups of By) without re-parsing.
+// An *Expr is not safe for concurrent use.
+type Expr struct {
+	name  string // name of the result column
+	expr  string // source expression
+	start int    // offset of the right-hand side within expr
+
+	bound map[string]any // parameters set by Bind
+
+	ot *opTree
+
+	dt DataTypes // data type of the result
+}
+
+// Compile compiles expr, which has the form
+//
+//	<result> := <expression>
+//
+// The functions in fns are the functions available to the expression.
+func Compile(expr string, fns Fns) (*Expr, error) {
 	var indx int
 
 	if indx = strings.Index(expr, ":="); indx < 0 {
-		return fmt.Errorf("expression has no assignment")
+		return nil, &ParseError{Expr: expr, Pos: -1, Msg: "expression has no assignment"}
 	}
 
 	left, right := strings.ReplaceAll(expr[:indx], " ", ""), expr[indx+2:]
 
-	ot := newOpTree(right, df.Fns())
+	ex := &Expr{name: left, expr: expr, start: indx + 2, ot: newOpTree(right, fns), dt: DTunknown}
 
-	if ex := ot.build(); ex != nil {
-		return ex
+	if e := ex.ot.build(); e != nil {
+		return nil, ex.locate(e)
 	}
 
-	if ex := ot.eval(df); ex != nil {
-		return ex
+	if e := ex.ot.resolve(); e != nil {
+		return nil, ex.locate(e)
 	}
 
-	if ot.value == nil {
-		return fmt.Errorf("parse error")
+	ex.dt = ex.ot.infer(nil)
+
+	return ex, nil
+}
+
+// Bind sets the values of the parameters (e.g. @rate) in the expression.  They are used by each later Eval.
+func (ex *Expr) Bind(params map[string]any) *Expr {
+	ex.bound = params
+	ex.dt = ex.ot.infer(params)
+
+	return ex
+}
+
+// DataType returns the data type of the result.  Compile and Bind resolve it from the function signatures, the
+// constants and the bound parameters.  If it depends on the types of columns (e.g. x+1, with x int or float),
+// it is DTunknown until the expression is evaluated.
+func (ex *Expr) DataType() DataTypes {
+	return ex.dt
+}
+
+// Eval evaluates the expression over df and appends the result to df.
+func (ex *Expr) Eval(df DF) error {
+	var (
+		val Column
+		e   error
+	)
+	if val, e = ex.Value(df); e != nil {
+		return e
 	}
 
 	// Assign parent and dialect
 	// If the parent isn't nil, that means we have a direct assignment like "a:=b" and we need to copy the column
-	if ot.value.Parent() != nil {
-		ot.value = ot.value.Copy()
+	if val.Parent() != nil {
+		val = val.Copy()
 	}
 
 	// need to assign parent here so AppendColumns will work for sql
-	_ = ColParent(df)(ot.value)
-	_ = ColDialect(df.Dialect())(ot.value)
+	_ = ColParent(df)(val)
+	_ = ColDialect(df.Dialect())(val)
 
 	// Need to drop existing column here so Rename will work
-	if df.Column(left) != nil {
-		_ = df.DropColumns(left)
+	if df.Column(ex.name) != nil {
+		_ = df.DropColumns(ex.name)
 	}
 
-	if e := ot.value.Rename(left); e != nil {
+	if e := val.Rename(ex.name); e != nil {
 		return e
 	}
 
-	return df.AppendColumn(ot.value, false)
+	return df.AppendColumn(val, false)
+}
+
+// Name returns the name of the result column.
+func (ex *Expr) Name() string {
+	return ex.name
+}
+
+func (ex *Expr) String() string {
+	return ex.expr
+}
+
+// Value evaluates the expression over df and returns the result without appending it to df.
+func (ex *Expr) Value(df DF) (Column, error) {
+	if e := ex.ot.eval(df, ex.bound); e != nil {
+		return nil, ex.locate(e)
+	}
+
+	if ex.ot.value == nil {
+		return nil, fmt.Errorf("parse error")
+	}
+
+	ex.dt = ex.ot.value.DataType()
+
+	return ex.ot.value, nil
 }
 
-// doOp runs a function
-func doOp(df DF, opName string, inputs ...Column) (Column, error) {
-	var fn Fn
+// locate fills in where a *ParseError occurred within the expression.  Other errors are returned as is.
+func (ex *Expr) locate(e error) error {
+	pe, ok := e.(*ParseError)
+	if !ok {
+		return e
+	}
+
+	pe.Expr, pe.Pos = ex.expr, -1
+
+	// the tree works on the expression with the spaces removed
+	var (
+		rhs []byte
+		idx []int // location in ex.expr of each byte of rhs
+	)
+	haveQuote := false
+	for ind := ex.start; ind < len(ex.expr); ind++ {
+		if ex.expr[ind] == '\'' {
+			haveQuote = !haveQuote
+		}
+
+		if ex.expr[ind] != ' ' || haveQuote {
+			rhs = append(rhs, ex.expr[ind])
+			idx = append(idx, ind)
+		}
+	}
 
-	if fn = df.Fns().Get(opName); fn == nil {
-		return nil, fmt.Errorf("op %s not defined, operation skipped", opName)
+	at := pe.at
+	if !pe.located || at < 0 || at >= len(idx) {
+		return pe
 	}
 
-	return RunDFfn(fn, df, inputs)
+	pe.Pos, pe.width = idx[at], 1
+	if end := at + len(pe.Token) - 1; end > at && end < len(idx) {
+		pe.width = idx[end] - idx[at] + 1
+	}
+
+	return pe
+}
+
+// doOp runs the function resolved for the node
+func (ot *opTree) doOp(df DF, inputs ...Column) (Column, error) {
+	var dts []DataTypes
+	for _, inp := range inputs {
+		if inp == nil {
+			break
+		}
+
+		dts = append(dts, inp.DataType())
+	}
+
+	if len(dts) == len(inputs) {
+		if _, e := matchSignature(ot.info, dts); e != nil {
+			return nil, ot.mark(e)
+		}
+	}
+
+	var fnR *FnReturn
+	if fnR = ot.fn(false, df, inputs...); fnR.Err != nil {
+		return nil, fnR.Err
+	}
+
+	return fnR.Value, nil
 }
 
 // opTree parses expressions.  The process has two steps:
@@ -88,6 +296,15 @@ type opTree struct {
 	ops operations // available operations (e.g. +, -, ...)
 
 	dependencies []string // columns required to evaluate this node.
+
+	at   int    // offset of expr within the expression of the root of the tree
+	opAt int    // offset of op within expr
+	lead string // leading - or ! that expr was rewritten from, e.g. -x is neg(x)
+
+	fn   Fn        // function resolved for fnName or op
+	info *FnReturn // info return of fn
+
+	konst any // value of the leaf if it is a constant
 }
 
 // operations is a list of operations available.  It is in order of
@@ -161,10 +378,13 @@ func (ot *opTree) build() error {
 		return nil
 	}
 
+	ot.opAt = len(l)
+
 	// recurse on left and right
 	if l != "" {
 		ot.left = &opTree{
 			expr:    l,
+			at:      ot.at,
 			funcs:   ot.funcs,
 			ops:     ot.ops,
 			fnNames: ot.fnNames,
@@ -178,6 +398,7 @@ func (ot *opTree) build() error {
 	if r != "" {
 		ot.right = &opTree{
 			expr:    r,
+			at:      ot.at + len(l) + len(ot.op),
 			funcs:   ot.funcs,
 			ops:     ot.ops,
 			fnNames: ot.fnNames,
@@ -189,16 +410,25 @@ func (ot *opTree) build() error {
 	}
 
 	if (ot.left == nil && ot.right != nil) || (ot.left != nil && ot.right == nil) {
-		return fmt.Errorf("invalid expression: %s", ot.expr)
+		return ot.mark(newParseError(ot.expr, fmt.Sprintf("invalid expression: %s", ot.expr)))
 	}
 
 	return nil
 }
 
 // eval evaluates the expression over the dataframe df
-func (ot *opTree) eval(df DF) error {
-	// bottom level -- either a constant or a member of df
+func (ot *opTree) eval(df DF, params map[string]any) error {
+	ot.value, ot.dependencies = nil, nil
+
+	// bottom level -- either a constant, a parameter or a member of df
 	if ot.op == "" && ot.fnName == "" {
+		if isParam(ot.expr) {
+			var e error
+			ot.value, e = ot.param(params)
+
+			return e
+		}
+
 		if c := df.Column(ot.expr); c != nil {
 			ot.dependencies = nodupAppend(ot.dependencies, c.Name())
 			ot.value = newParsed(c, ot.dependencies...)
@@ -207,7 +437,7 @@ func (ot *opTree) eval(df DF) error {
 
 		var e error
 		if ot.value, e = ot.constant(ot.expr); e != nil {
-			return e
+			return ot.leafError(df, e)
 		}
 
 		return nil
@@ -221,7 +451,7 @@ func (ot *opTree) eval(df DF) error {
 	// handle function call
 	if ot.fnName != "" {
 		for ind := range len(ot.inputs) {
-			if e := ot.inputs[ind].eval(df); e != nil {
+			if e := ot.inputs[ind].eval(df, params); e != nil {
 				return e
 			}
 		}
@@ -232,7 +462,7 @@ func (ot *opTree) eval(df DF) error {
 			ot.dependencies = nodupAppend(ot.dependencies, ot.inputs[ind].dependencies...)
 		}
 
-		if c, ex = doOp(df, ot.fnName, inp...); ex != nil {
+		if c, ex = ot.doOp(df, inp...); ex != nil {
 			return ex
 		}
 
@@ -243,11 +473,11 @@ func (ot *opTree) eval(df DF) error {
 
 	// Do left/right eval then op for this node
 	if ot.left != nil {
-		if e := ot.left.eval(df); e != nil {
+		if e := ot.left.eval(df, params); e != nil {
 			return e
 		}
 
-		if e := ot.right.eval(df); e != nil {
+		if e := ot.right.eval(df, params); e != nil {
 			return e
 		}
 	}
@@ -266,7 +496,7 @@ func (ot *opTree) eval(df DF) error {
 	}
 
 	// run op on left/right
-	if c, ex = doOp(df, mapOp(ot.op), vl, vr); ex != nil {
+	if c, ex = ot.doOp(df, vl, vr); ex != nil {
 		return ex
 	}
 
@@ -275,6 +505,143 @@ func (ot *opTree) eval(df DF) error {
 	return nil
 }
 
+// check determines the data type of the node from the column types of df and the info returns of the
+// functions without evaluating the node.
+func (ot *opTree) check(df DF, params map[string]any) (dt DataTypes, isScalar bool, err error) {
+	ot.dependencies = nil
+
+	// leaf
+	if ot.fn == nil {
+		if isParam(ot.expr) {
+			c, e := ot.param(params)
+			if e != nil {
+				return DTunknown, false, e
+			}
+
+			return c.DataType(), true, nil
+		}
+
+		if c := df.Column(ot.expr); c != nil {
+			ot.dependencies = []string{c.Name()}
+			return c.DataType(), false, nil
+		}
+
+		var (
+			c Column
+			e error
+		)
+		if c, e = ot.constant(ot.expr); e != nil {
+			return DTunknown, false, ot.leafError(df, e)
+		}
+
+		return c.DataType(), true, nil
+	}
+
+	nodes := ot.inputs
+	if ot.fnName == "" {
+		nodes = []*opTree{ot.left, ot.right}
+	}
+
+	var dts []DataTypes
+	// a function with no inputs (e.g. rowNumber()) returns a column
+	isScalar = len(nodes) > 0
+	for _, node := range nodes {
+		var (
+			dtn     DataTypes
+			scalarn bool
+			e       error
+		)
+		if dtn, scalarn, e = node.check(df, params); e != nil {
+			return DTunknown, false, e
+		}
+
+		dts = append(dts, dtn)
+		isScalar = isScalar && scalarn
+		ot.dependencies = nodupAppend(ot.dependencies, node.dependencies...)
+	}
+
+	if dt, err = matchSignature(ot.info, dts); err != nil {
+		return DTunknown, false, ot.mark(err)
+	}
+
+	return dt, isScalar || ot.info.IsScalar, nil
+}
+
+// infer returns the data type of the node that follows from the function signatures, the constants and params
+// alone.  It returns DTunknown if that depends on the types of columns or if no signature fits, which Eval reports.
+func (ot *opTree) infer(params map[string]any) DataTypes {
+	// leaf
+	if ot.fn == nil {
+		var (
+			c Column
+			e error
+		)
+		switch {
+		case isParam(ot.expr):
+			c, e = ot.param(params)
+		default:
+			c, e = ot.constant(ot.expr)
+		}
+
+		if e != nil || c == nil {
+			return DTunknown
+		}
+
+		return c.DataType()
+	}
+
+	nodes := ot.inputs
+	if ot.fnName == "" {
+		nodes = []*opTree{ot.left, ot.right}
+	}
+
+	var dts []DataTypes
+	for _, node := range nodes {
+		dts = append(dts, node.infer(params))
+	}
+
+	if ot.info.Inputs == nil {
+		dt, _ := matchSignature(ot.info, dts)
+		return dt
+	}
+
+	// the output must be the same for every signature that fits
+	out := DTunknown
+	for ind, sig := range ot.info.Inputs {
+		if !sigFits(ot.info, sig, dts) {
+			continue
+		}
+
+		dt := ot.info.Output[ind]
+		if dt == DTunknown && len(dts) > 0 {
+			dt = dts[0]
+		}
+
+		if dt == DTunknown || (out != DTunknown && dt != out) {
+			return DTunknown
+		}
+
+		out = dt
+	}
+
+	return out
+}
+
+// categorical returns true if the tree creates a categorical column.
+func (ot *opTree) categorical() bool {
+	if ot.info != nil && Has(DTcategorical, ot.info.Output) {
+		return true
+	}
+
+	for _, node := range append([]*opTree{ot.left, ot.right}, ot.inputs...) {
+		if node != nil && node.categorical() {
+			return true
+		}
+	}
+
+	return false
+}
+
 // constant handles the leaf of the opTree when it is a constant.
 // strings are surrounded by single quotes
 func (ot *opTree) constant(xIn string) (Column, error) {
@@ -282,26 +649,147 @@ func (ot *opTree) constant(xIn string) (Column, error) {
 		return nil, nil
 	}
 
-	if len(xIn) >= 2 && xIn[0:1] == "'" && xIn[len(xIn)-1:] == "'" {
-		xIn = strings.TrimSuffix(strings.TrimPrefix(xIn, "'"), "'")
-		c, _ := NewScalar(xIn)
-		return newParsed(c), nil
+	// the value is worked out once, but each evaluation gets its own *Scalar
+	if ot.konst == nil {
+		if len(xIn) >= 2 && xIn[0:1] == "'" && xIn[len(xIn)-1:] == "'" {
+			// a double single quote means keep a single quote in the string
+			ot.konst = strings.ReplaceAll(strings.TrimSuffix(strings.TrimPrefix(xIn, "'"), "'"), "''", "'")
+		} else {
+			var (
+				v  any
+				dt DataTypes
+				e  error
+			)
+			if v, dt, e = bestType(xIn, false); e != nil || dt == DTunknown || dt == DTstring {
+				return nil, newParseError(xIn, fmt.Sprintf("cannot interpret %v as a constant...missing function?", xIn))
+			}
+
+			ot.konst = v
+		}
 	}
 
+	c, _ := NewScalar(ot.konst)
+
+	return newParsed(c), nil
+}
+
+// param returns the value of the parameter in the leaf as a scalar.
+func (ot *opTree) param(params map[string]any) (Column, error) {
+	name := ot.expr[1:]
+
 	var (
-		v  any
-		dt DataTypes
-		e  error
+		val any
+		ok  bool
 	)
-	if v, dt, e = bestType(xIn, false); e != nil || dt == DTunknown || dt == DTstring {
-		return nil, fmt.Errorf("cannot interpret %v as a constant...missing function?", xIn)
+	if val, ok = params[name]; !ok {
+		if val, ok = params[ot.expr]; !ok {
+			pe := newParseError(ot.expr, fmt.Sprintf("parameter %s is not bound", ot.expr))
+			var names []string
+			for k := range params {
+				names = append(names, k)
+			}
+
+			sort.Strings(names)
+			pe.Suggestions = suggest(name, names)
+
+			return nil, ot.mark(pe)
+		}
+	}
+
+	var e error
+	if val, e = paramValue(val); e != nil {
+		return nil, ot.mark(newParseError(ot.expr, fmt.Sprintf("parameter %s: %v", ot.expr, e)))
 	}
 
-	c, _ := NewScalar(v)
+	c, _ := NewScalar(val)
 
 	return newParsed(c), nil
 }
 
+// resolve looks up the functions the tree uses and checks the number of arguments to each.
+func (ot *opTree) resolve() error {
+	for _, node := range append([]*opTree{ot.left, ot.right}, ot.inputs...) {
+		if node == nil {
+			continue
+		}
+
+		if e := node.resolve(); e != nil {
+			return e
+		}
+	}
+
+	var (
+		name  string
+		nArgs int
+	)
+	switch {
+	case ot.fnName != "":
+		name, nArgs = ot.fnName, len(ot.inputs)
+	case ot.op != "":
+		name, nArgs = mapOp(ot.op), 2
+	default:
+		// leaf -- column names can't have parens, so this must be a call to an unknown function
+		if i := strings.Index(ot.expr, "("); i > 0 && ot.expr[0] != '\'' {
+			pe := newParseError(ot.expr[:i], fmt.Sprintf("function %s not defined", ot.expr[:i]))
+			var names []string
+			for _, fnName := range ot.fnNames {
+				names = append(names, strings.TrimSuffix(fnName, "("))
+			}
+
+			pe.Suggestions = suggest(ot.expr[:i], names)
+
+			return ot.mark(pe)
+		}
+
+		return nil
+	}
+
+	if ot.fn = ot.funcs.Get(name); ot.fn == nil {
+		return ot.mark(newParseError(name, fmt.Sprintf("op %s not defined, operation skipped", name)))
+	}
+
+	ot.info = ot.fn(true, nil)
+
+	return ot.mark(checkArgs(ot.info, nArgs))
+}
+
+// mark records the node of the tree where the *ParseError e occurred.
+func (ot *opTree) mark(e error) error {
+	pe, ok := e.(*ParseError)
+	if !ok || pe.located {
+		return e
+	}
+
+	switch {
+	case ot.lead != "":
+		// point at the leading operator rather than the function it was rewritten as
+		pe.Token, pe.off = ot.lead, len("neg(")-len(ot.lead)
+	case ot.fnName == "" && ot.op != "":
+		// point at the operator rather than the name of the function that implements it
+		pe.Token, pe.off = ot.op, ot.opAt
+	}
+
+	pe.located, pe.at = true, ot.at+pe.off
+
+	return pe
+}
+
+// leafError adds suggestions to the error from a leaf that is neither a column of df nor a constant.
+func (ot *opTree) leafError(df DF, e error) error {
+	pe, ok := e.(*ParseError)
+	if !ok || ot.expr == "" {
+		return e
+	}
+
+	// a leaf starting with a letter was meant to be a column
+	if c := ot.expr[0]; c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') {
+		pe.Msg = fmt.Sprintf("%s is not a column or a constant", ot.expr)
+		pe.Suggestions = suggest(ot.expr, df.ColumnNames())
+	}
+
+	return ot.mark(pe)
+}
+
 // outerParen strips away parentheses that surround the entire expression.
 // For example, ((a+b)) becomes a+b
 // but (a+b)*3 is not changed.
@@ -341,7 +829,8 @@ func (ot *opTree) isFunction() (haveFn bool, fnOp string) {
 // or a leaf and fills in the appropriate fields of ot.
 func (ot *opTree) scan() (left, right, op string, err error) {
 	// strip outer parens
-	ot.expr = ot.outerParen(ot.expr)
+	stripped := ot.outerParen(ot.expr)
+	ot.expr, ot.at = stripped, ot.at+(len(ot.expr)-len(stripped))/2
 
 	var leadingOp bool
 	// break into two expressions, if there are two
@@ -351,13 +840,17 @@ func (ot *opTree) scan() (left, right, op string, err error) {
 	if leadingOp {
 		switch op {
 		case "+":
-			ot.expr = right
-		case "-":
-			ot.expr = fmt.Sprintf("neg(%s)", right)
-		case "!":
-			ot.expr = fmt.Sprintf("not(%s)", right)
+			ot.expr, ot.at = right, ot.at+len(op)
+		case "-", "!":
+			fn := "neg"
+			if op == "!" {
+				fn = "not"
+			}
+
+			// right stays where it is in the expression: fn( replaces op
+			ot.expr, ot.lead, ot.at = fmt.Sprintf("%s(%s)", fn, right), op, ot.at+len(op)-len(fn+"(")
 		default:
-			return "", "", "", fmt.Errorf("invalid leading operation: %s", op)
+			return "", "", "", ot.mark(newParseError(op, fmt.Sprintf("invalid leading operation: %s", op)))
 		}
 
 		op = ""
@@ -391,7 +884,7 @@ func (ot *opTree) args(xIn string) ([]string, error) {
 
 		if depth == 0 && xIn[ind] == ',' {
 			if arg = xIn[start:ind]; arg == "" {
-				return nil, fmt.Errorf("bad arguments: %s", xIn)
+				return nil, newParseError(xIn, fmt.Sprintf("bad arguments: %s", xIn))
 			}
 
 			xOut = append(xOut, arg)
@@ -419,17 +912,25 @@ func (ot *opTree) makeFn(fnName string) error {
 		e error
 	)
 	if x, e = ot.args(inner); e != nil {
-		return e
+		if pe, ok := e.(*ParseError); ok {
+			pe.off = len(fnName)
+		}
+
+		return ot.mark(e)
 	}
 
 	ot.fnName, ot.op = fnName[:len(fnName)-1], ""
 
+	at := ot.at + len(fnName) // offset of the argument within the expression
 	for ind := range len(x) {
+		argAt := at
+		at += len(x[ind]) + 1
+
 		if x[ind] == "" {
 			continue
 		}
 
-		op := newOpTree(x[ind], ot.funcs)
+		op := &opTree{expr: compress(x[ind], " "), at: argAt, funcs: ot.funcs, ops: ot.ops, fnNames: ot.fnNames}
 
 		if ex := op.build(); ex != nil {
 			return ex
@@ -441,9 +942,10 @@ func (ot *opTree) makeFn(fnName string) error {
 	return nil
 }
 
-// parenError checks for matching parentheses.
+// parenError checks for matching parentheses.  The error points at the first unmatched parenthesis.
 func (ot *opTree) parenError() error {
-	haveQuote, count := false, 0
+	var open []int // locations of the unclosed left parens
+	haveQuote := false
 	for ind := range len(ot.expr) {
 		char := ot.expr[ind : ind+1]
 		if char == "'" {
@@ -454,22 +956,67 @@ func (ot *opTree) parenError() error {
 			continue
 		}
 
-		if char == ")" {
-			count++
+		if char == "(" {
+			open = append(open, ind)
 		}
 
-		if char == "(" {
-			count--
+		if char == ")" {
+			if len(open) == 0 {
+				pe := newParseError(char, fmt.Sprintf("mis-matched parens in %s", ot.expr))
+				pe.off = ind
+
+				return ot.mark(pe)
+			}
+
+			open = open[:len(open)-1]
 		}
 	}
 
-	if count != 0 {
-		return fmt.Errorf("mis-matched parens in %s", ot.expr)
+	if len(open) > 0 {
+		pe := newParseError("(", fmt.Sprintf("mis-matched parens in %s", ot.expr))
+		pe.off = open[0]
+
+		return ot.mark(pe)
 	}
 
 	return nil
 }
 
+// isParam returns true if x is a parameter, e.g. @rate or $rate.
+func isParam(x string) bool {
+	if len(x) < 2 || (x[0] != '@' && x[0] != '$') {
+		return false
+	}
+
+	for _, char := range x[1:] {
+		if char != '_' && !unicode.IsLetter(char) && !unicode.IsDigit(char) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// paramValue converts the value of a parameter to one of the types the parser works with.
+func paramValue(val any) (any, error) {
+	if dt := WhatAmI(val); dt != DTunknown && reflect.ValueOf(val).Kind() != reflect.Slice {
+		return val, nil
+	}
+
+	xv := reflect.ValueOf(val)
+	if xv.CanInt() || xv.CanUint() {
+		v, _ := toInt(val)
+		return v, nil
+	}
+
+	if xv.CanFloat() {
+		v, _ := toFloat(val)
+		return v, nil
+	}
+
+	return nil, fmt.Errorf("unsupported type %T", val)
+}
+
 // ************** operations methods **************
 
 // trailingOp checks whether the end of expr is an operation
@@ -605,3 +1152,198 @@ func compress(x, target string) string {
 
 	return out
 }
+
+// sigFits returns true if inputs of types dts fit the signature sig of the function described by info.
+func sigFits(info *FnReturn, sig, dts []DataTypes) bool {
+	if (!info.Varying && len(sig) != len(dts)) || len(sig) > len(dts) {
+		return false
+	}
+
+	for k := range len(sig) {
+		// categorical columns are ints as far as function inputs go.  An input of unknown type can't be checked.
+		if sig[k] != DTunknown && dts[k] != DTunknown && sig[k] != dts[k] && !(sig[k] == DTint && dts[k] == DTcategorical) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// matchSignature finds the output type of the function described by info given the input types dts.
+func matchSignature(info *FnReturn, dts []DataTypes) (DataTypes, error) {
+	if info.Inputs == nil {
+		if len(info.Output) == 0 {
+			return DTunknown, nil
+		}
+
+		return info.Output[0], nil
+	}
+
+	for ind, sig := range info.Inputs {
+		if !sigFits(info, sig, dts) {
+			continue
+		}
+
+		// an unknown output is the same type as the input (e.g. global)
+		if out := info.Output[ind]; out != DTunknown || len(dts) == 0 {
+			return out, nil
+		}
+
+		return dts[0], nil
+	}
+
+	var types []string
+	for _, dt := range dts {
+		types = append(types, typeName(dt))
+	}
+
+	pe := newParseError(info.Name, fmt.Sprintf("no version of %s takes inputs (%s)", info.Name, strings.Join(types, ",")))
+	pe.Expected = signatures(info)
+
+	return DTunknown, pe
+}
+
+// ************** ParseError **************
+
+// ParseError is the error returned when an expression can't be parsed or its functions can't be applied to
+// the inputs.  Error() shows the expression with the offending piece marked.
+type ParseError struct {
+	Expr        string   // expression being parsed
+	Pos         int      // byte offset of Token within Expr, -1 if not known
+	Token       string   // the offending piece of the expression
+	Msg         string   // description of the problem
+	Expected    []string // signatures of the function, e.g. abs(float)
+	Suggestions []string // known names close to Token
+
+	located bool // true if the opTree node where the error occurred has set at
+	at      int  // offset of Token within the expression with the spaces removed
+	off     int  // offset of Token within the expression of the node
+	width   int  // width of Token within Expr
+}
+
+func newParseError(token, msg string) *ParseError {
+	return &ParseError{Pos: -1, Token: token, Msg: msg}
+}
+
+func (pe *ParseError) Error() string {
+	msg := pe.Msg
+	if pe.Pos >= 0 {
+		msg += fmt.Sprintf(" at position %d", pe.Pos)
+	}
+
+	if len(pe.Suggestions) > 0 {
+		msg += fmt.Sprintf("; did you mean %s?", strings.Join(pe.Suggestions, " or "))
+	}
+
+	if len(pe.Expected) > 0 {
+		msg += "; expected " + strings.Join(pe.Expected, ", ")
+	}
+
+	if pe.Pos < 0 || pe.Pos >= len(pe.Expr) {
+		return msg
+	}
+
+	// keep tabs so the caret lines up
+	pad := []byte(pe.Expr[:pe.Pos])
+	for ind := range pad {
+		if pad[ind] != '\t' {
+			pad[ind] = ' '
+		}
+	}
+
+	return fmt.Sprintf("%s\n\t%s\n\t%s%s", msg, pe.Expr, pad, strings.Repeat("^", max(pe.width, 1)))
+}
+
+// signatures returns the ways the function described by info can be called, e.g. abs(float).
+func signatures(info *FnReturn) []string {
+	var sigs []string
+	for _, sig := range info.Inputs {
+		var types []string
+		for _, dt := range sig {
+			types = append(types, typeName(dt))
+		}
+
+		if info.Varying {
+			types = append(types, "...")
+		}
+
+		sigs = append(sigs, fmt.Sprintf("%s(%s)", info.Name, strings.Join(types, ",")))
+	}
+
+	return sigs
+}
+
+// typeName is the name of dt as it appears in function specs.
+func typeName(dt DataTypes) string {
+	if dt == DTunknown {
+		return "any"
+	}
+
+	return strings.TrimPrefix(dt.String(), "DT")
+}
+
+// suggest returns the candidates that are close to name, closest first.
+func suggest(name string, candidates []string) []string {
+	const maxSuggest = 3
+
+	// allow one edit for short names, more for longer ones
+	maxDist := max(1, len(name)/3)
+
+	type near struct {
+		name string
+		dist int
+	}
+
+	var nears []near
+	for _, cand := range candidates {
+		if cand == name {
+			continue
+		}
+
+		if dist := editDistance(strings.ToLower(name), strings.ToLower(cand)); dist <= maxDist {
+			nears = append(nears, near{cand, dist})
+		}
+	}
+
+	sort.SliceStable(nears, func(i, j int) bool { return nears[i].dist < nears[j].dist })
+
+	var out []string
+	for ind := 0; ind < len(nears) && len(out) < maxSuggest; ind++ {
+		if !Has(nears[ind].name, out) {
+			out = append(out, nears[ind].name)
+		}
+	}
+
+	return out
+}
+
+// editDistance is the number of insertions, deletions, substitutions and transpositions of adjacent
+// characters needed to turn a into b.
+func editDistance(a, b string) int {
+	dist := make([][]int, len(a)+1)
+	for i := range dist {
+		dist[i] = make([]int, len(b)+1)
+		dist[i][0] = i
+	}
+
+	for j := range len(b) + 1 {
+		dist[0][j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			dist[i][j] = min(dist[i-1][j]+1, dist[i][j-1]+1, dist[i-1][j-1]+cost)
+
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				dist[i][j] = min(dist[i][j], dist[i-2][j-2]+1)
+			}
+		}
+	}
+
+	return dist[len(a)][len(b)]
+}
@@ -0,0 +1,199 @@
+package df
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileSchema describes the layout of a file.  It is saved as a JSON or YAML sidecar by SaveSchema and read
+// by FileUseSchema so the file can be opened without detecting field types.
+type FileSchema struct {
+	Header      bool          `json:"header" yaml:"header"`
+	Sep         string        `json:"sep,omitempty" yaml:"sep,omitempty"`
+	EOL         string        `json:"eol,omitempty" yaml:"eol,omitempty"`
+	StringDelim string        `json:"stringDelim,omitempty" yaml:"stringDelim,omitempty"`
+	Fields      []FieldSchema `json:"fields" yaml:"fields"`
+}
+
+// FieldSchema describes a field of a file.  Width is set for fixed-width files.  DateFormat is set for dates in a
+// saved file and for fields with FileColumnDateFormat.
+type FieldSchema struct {
+	Name       string `json:"name" yaml:"name"`
+	Type       string `json:"type" yaml:"type"`
+	Width      int    `json:"width,omitempty" yaml:"width,omitempty"`
+	DateFormat string `json:"dateFormat,omitempty" yaml:"dateFormat,omitempty"`
+}
+
+// FileUseSchema sets the field names, types and widths and the header, separator, EOL and string delimiter
+// from the sidecar fileName written by SaveSchema.  Files ending in .yaml or .yml are YAML, others JSON.
+func FileUseSchema(fileName string) FileOpt {
+	return func(f *Files) error {
+		var (
+			b []byte
+			e error
+		)
+		if b, e = os.ReadFile(fileName); e != nil {
+			return e
+		}
+
+		sch := &FileSchema{}
+		if isYAML(fileName) {
+			e = yaml.Unmarshal(b, sch)
+		} else {
+			e = json.Unmarshal(b, sch)
+		}
+
+		if e != nil {
+			return fmt.Errorf("reading schema %s: %w", fileName, e)
+		}
+
+		return f.setSchema(sch)
+	}
+}
+
+// Schema returns the schema of the file as it was opened or saved.
+func (f *Files) Schema() *FileSchema {
+	sch := &FileSchema{
+		Header:      f.header,
+		Sep:         byteString(f.sep),
+		EOL:         byteString(f.eol),
+		StringDelim: byteString(f.stringDelim),
+	}
+
+	for ind, fn := range f.FieldNames() {
+		fld := FieldSchema{Name: fn, Type: f.FieldTypes()[ind].String()}
+		if f.FieldWidths() != nil {
+			fld.Width = f.FieldWidths()[ind]
+		}
+
+		if f.FieldTypes()[ind] == DTdate {
+			if cf := f.formats[fn]; cf != nil && cf.date != "" {
+				fld.DateFormat = cf.date
+			} else if f.wtr != nil {
+				// the format dates were written with
+				fld.DateFormat = f.dateFormat
+			}
+		}
+
+		sch.Fields = append(sch.Fields, fld)
+	}
+
+	return sch
+}
+
+// SaveSchema saves the schema of the file to the sidecar fileName.  Files ending in .yaml or .yml are YAML,
+// others JSON.
+func (f *Files) SaveSchema(fileName string) error {
+	var (
+		b []byte
+		e error
+	)
+	if isYAML(fileName) {
+		b, e = yaml.Marshal(f.Schema())
+	} else {
+		b, e = json.MarshalIndent(f.Schema(), "", "  ")
+	}
+
+	if e != nil {
+		return e
+	}
+
+	return os.WriteFile(fileName, b, 0o644)
+}
+
+func (f *Files) setSchema(sch *FileSchema) error {
+	var (
+		names  []string
+		types  []DataTypes
+		widths []int
+	)
+	for _, fld := range sch.Fields {
+		if e := validName(fld.Name); e != nil {
+			return e
+		}
+
+		dt := DTFromString(fld.Type)
+		if dt != DTfloat && dt != DTint && dt != DTstring && dt != DTdate {
+			return fmt.Errorf("unsupported type %s for field %s in schema", fld.Type, fld.Name)
+		}
+
+		if fld.DateFormat != "" {
+			if e := FileColumnDateFormat(fld.Name, fld.DateFormat)(f); e != nil {
+				return e
+			}
+		}
+
+		names = append(names, fld.Name)
+		types = append(types, dt)
+		widths = append(widths, fld.Width)
+	}
+
+	if len(names) == 0 {
+		return fmt.Errorf("schema has no fields")
+	}
+
+	// widths are set only for fixed-width files
+	if !Has(0, widths) {
+		if e := FileFieldWidths(widths)(f); e != nil {
+			return e
+		}
+	}
+
+	// an empty EOL or string delimiter means there is none
+	var e error
+	if sch.Sep != "" {
+		if f.sep, e = stringByte(sch.Sep); e != nil {
+			return e
+		}
+	}
+
+	if f.eol, e = stringByte(sch.EOL); e != nil {
+		return e
+	}
+
+	if f.stringDelim, e = stringByte(sch.StringDelim); e != nil {
+		return e
+	}
+
+	f.fieldNames, f.fieldTypes, f.header = names, types, sch.Header
+
+	return nil
+}
+
+func isYAML(fileName string) bool {
+	ext := filepath.Ext(fileName)
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// byteString returns b as an escaped string, e.g. \n for a newline, with 0 as "".
+func byteString(b byte) string {
+	if b == 0 {
+		return ""
+	}
+
+	q := strconv.Quote(string([]byte{b}))
+
+	return q[1 : len(q)-1]
+}
+
+// stringByte is the inverse of byteString.
+func stringByte(s string) (byte, error) {
+	if s == "" {
+		return 0, nil
+	}
+
+	var (
+		u string
+		e error
+	)
+	if u, e = strconv.Unquote(`"` + s + `"`); e != nil || len(u) != 1 {
+		return 0, fmt.Errorf("%s is not a single character", s)
+	}
+
+	return u[0], nil
+}
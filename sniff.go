@@ -0,0 +1,289 @@
+package df
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// Encodings of files.
+const (
+	EncodingUTF8    = "utf-8"
+	EncodingLatin1  = "latin1"
+	EncodingWin1252 = "windows-1252"
+	EncodingUTF16   = "utf-16" // byte order given by a BOM
+	EncodingUTF16LE = "utf-16le"
+	EncodingUTF16BE = "utf-16be"
+)
+
+// sniffSeps are the separators sniffing chooses among, in order of preference for ties.
+var sniffSeps = []byte{',', '\t', '|', ';'}
+
+// sniffBytes is the # of bytes examined when sniffing.
+const sniffBytes = 64 * 1024
+
+// FileEncoding sets the encoding of the file.  Input is decoded to UTF-8 when read.  Default is UTF-8.
+func FileEncoding(enc string) FileOpt {
+	return func(f *Files) error {
+		if _, e := decoder(enc); e != nil {
+			return e
+		}
+
+		f.encoding = strings.ToLower(enc)
+
+		return nil
+	}
+}
+
+// FileSniff sets whether Open infers the encoding, the separator (among , tab | ;) and whether the file has a
+// header.  A header is assumed if its values don't fit the types of the following rows.  If there is no
+// header and no field names are given, the fields are named col1, col2, ...  Default is false.
+func FileSniff(sniff bool) FileOpt {
+	return func(f *Files) error {
+		f.sniff = sniff
+
+		return nil
+	}
+}
+
+// decode wraps the reader to decode the encoding set by FileEncoding or, if sniffing, the one inferred.
+func (f *Files) decode() error {
+	enc := f.encoding
+	if enc == "" && f.sniff {
+		sample, _ := f.rdr.Peek(sniffBytes)
+		enc = sniffEncoding(sample)
+	}
+
+	// drop a UTF-8 BOM
+	if bom := []byte{0xef, 0xbb, 0xbf}; enc == "" || enc == EncodingUTF8 {
+		if b, _ := f.rdr.Peek(len(bom)); bytes.Equal(b, bom) {
+			_, _ = f.rdr.Discard(len(bom))
+		}
+
+		return nil
+	}
+
+	var (
+		dec *encoding.Decoder
+		e   error
+	)
+	if dec, e = decoder(enc); e != nil {
+		return e
+	}
+
+	f.rdr = bufio.NewReaderSize(transform.NewReader(f.rdr, dec), sniffBytes)
+
+	return nil
+}
+
+// sniffSep sets the separator to the candidate that occurs the same, non-zero, # of times on the most lines of
+// the start of the file.
+func (f *Files) sniffSep() {
+	sample, e := f.rdr.Peek(sniffBytes)
+	lines := bytes.Split(sample, []byte{f.eol})
+	if len(lines) > 1 && e == nil {
+		// the last line may be incomplete
+		lines = lines[:len(lines)-1]
+	}
+
+	best, bestScore := f.sep, 0
+	for _, sep := range sniffSeps {
+		counts := make(map[int]int)
+		for _, line := range lines {
+			if n := countSep(line, sep, f.stringDelim); n > 0 {
+				counts[n]++
+			}
+		}
+
+		for _, n := range counts {
+			if n > bestScore {
+				best, bestScore = sep, n
+			}
+		}
+	}
+
+	f.sep = best
+}
+
+// sniffHeader reads the first record and decides whether it is a header by comparing it to the types of the
+// records that follow.  If there is no header, the record is kept as data.
+func (f *Files) sniffHeader() error {
+	var (
+		first  []string
+		quoted []bool
+		e      error
+	)
+	if first, quoted, e = f.fields(); e != nil {
+		return fmt.Errorf("reading first record: %w", e)
+	}
+
+	line := f.recLine
+
+	for ind := range first {
+		first[ind] = strings.Trim(first[ind], " ")
+	}
+
+	names := f.fieldNames
+	if names == nil {
+		f.fieldNames = first
+	}
+
+	f.setFormats()
+
+	var (
+		counts  []*ctr
+		repeats bool
+	)
+	detected := f.fieldTypes == nil
+	if detected {
+		var e1 error
+		if counts, repeats, e1 = f.detect(first); e1 != nil {
+			return e1
+		}
+
+		f.setTypes(counts)
+	}
+
+	if len(f.FieldTypes()) != len(first) {
+		return fmt.Errorf("field names and field types aren't same length")
+	}
+
+	if f.header = f.isHeader(first, quoted, repeats); f.header {
+		if names == nil {
+			for _, fn := range first {
+				if e1 := validName(fn); e1 != nil {
+					return fmt.Errorf("header %s: %w", fn, e1)
+				}
+			}
+		}
+
+		return nil
+	}
+
+	if names == nil {
+		f.fieldNames = nil
+		for ind := range first {
+			f.fieldNames = append(f.fieldNames, fmt.Sprintf("col%d", ind+1))
+		}
+
+		f.setFormats()
+	}
+
+	r := record{vals: first, quoted: quoted, line: line}
+	f.peeked = append([]record{r}, f.peeked...)
+	if detected {
+		if e1 := f.count(counts, r); e1 != nil {
+			return e1
+		}
+
+		f.setTypes(counts)
+	}
+
+	return nil
+}
+
+// isHeader returns true if first doesn't look like data.  If any field isn't a string, first is a header if one
+// of those fields doesn't convert.  Otherwise, first is a header if its values are distinct, legal names that
+// don't repeat in the rows examined by detect.
+func (f *Files) isHeader(first []string, quoted []bool, repeats bool) bool {
+	typed := false
+	for ind, v := range first {
+		if dt := f.FieldTypes()[ind]; dt != DTstring {
+			typed = true
+			if _, ok := f.parse(v, ind, dt); !ok && (quoted[ind] || !Has(v, f.na)) {
+				return true
+			}
+		}
+	}
+
+	if typed {
+		return false
+	}
+
+	if repeats {
+		return false
+	}
+
+	for ind, v := range first {
+		if v == "" || validName(v) != nil || Position(v, first) != ind {
+			return false
+		}
+	}
+
+	return true
+}
+
+// countSep counts sep in line outside of quoted strings.
+func countSep(line []byte, sep, delim byte) int {
+	n, in := 0, false
+	for _, b := range line {
+		switch {
+		case delim != 0 && b == delim:
+			in = !in
+		case b == sep && !in:
+			n++
+		}
+	}
+
+	return n
+}
+
+// sniffEncoding infers the encoding from a BOM, the pattern of zero bytes or whether sample is valid UTF-8.
+func sniffEncoding(sample []byte) string {
+	switch {
+	case bytes.HasPrefix(sample, []byte{0xff, 0xfe}), bytes.HasPrefix(sample, []byte{0xfe, 0xff}):
+		return EncodingUTF16
+	case len(sample) < 2:
+		return EncodingUTF8
+	}
+
+	// ASCII text in UTF-16 has a zero in every other byte
+	var zeros [2]int
+	for ind, b := range sample {
+		if b == 0 {
+			zeros[ind%2]++
+		}
+	}
+
+	switch half := len(sample) / 4; {
+	case zeros[1] > half && zeros[0] == 0:
+		return EncodingUTF16LE
+	case zeros[0] > half && zeros[1] == 0:
+		return EncodingUTF16BE
+	}
+
+	// the sample may end within a character
+	for cut := 0; cut < utf8.UTFMax && cut < len(sample); cut++ {
+		if utf8.Valid(sample[:len(sample)-cut]) {
+			return EncodingUTF8
+		}
+	}
+
+	return EncodingWin1252
+}
+
+func decoder(enc string) (*encoding.Decoder, error) {
+	switch strings.ToLower(enc) {
+	case EncodingUTF8:
+		return encoding.Nop.NewDecoder(), nil
+	case EncodingLatin1, "iso-8859-1":
+		return charmap.ISO8859_1.NewDecoder(), nil
+	case EncodingWin1252, "cp1252":
+		return charmap.Windows1252.NewDecoder(), nil
+	case EncodingUTF16:
+		return unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM).NewDecoder(), nil
+	case EncodingUTF16LE:
+		return unicode.UTF16(unicode.LittleEndian, unicode.UseBOM).NewDecoder(), nil
+	case EncodingUTF16BE:
+		return unicode.UTF16(unicode.BigEndian, unicode.UseBOM).NewDecoder(), nil
+	default:
+		return nil, fmt.Errorf("unsupported encoding: %s", enc)
+	}
+}
@@ -1,9 +1,9 @@
 package sql
 
 import (
+	"context"
 	"fmt"
 	"iter"
-	"strings"
 
 	d "github.com/invertedv/df"
 	m "github.com/invertedv/df/mem"
@@ -69,15 +69,34 @@ func (c *Col) Core() *d.ColCore {
 	return c.ColCore
 }
 
-// Data runs the SQl to pull the data.
+// Data runs the SQl to pull the data. It panics if the query fails.
 func (c *Col) Data() *d.Vector {
 	return c.DataLimit(0)
 }
 
-// DataLimit pulls the first limit rows of data.  Pulls all the data if limit=0.
+// DataE runs the SQL to pull the data.
+func (c *Col) DataE() (*d.Vector, error) {
+	return c.DataLimitE(0)
+}
+
+// DataLimit pulls the first limit rows of data.  Pulls all the data if limit=0. It panics if the query fails.
 func (c *Col) DataLimit(limit int) *d.Vector {
+	var (
+		v *d.Vector
+		e error
+	)
+	if v, e = c.DataLimitE(limit); e != nil {
+		panic(e)
+	}
+
+	return v
+}
+
+// DataLimitE pulls the first limit rows of data.  Pulls all the data if limit=0.
+func (c *Col) DataLimitE(limit int) (*d.Vector, error) {
 	var (
 		df *m.DF
+		mq string
 		e  error
 	)
 
@@ -86,21 +105,24 @@ func (c *Col) DataLimit(limit int) *d.Vector {
 		_ = d.ColName(d.RandomLetters(5))(c)
 	}
 
-	mq := c.MakeQuery()
+	if mq, e = c.MakeQueryE(); e != nil {
+		return nil, e
+	}
+
 	if limit > 0 {
 		mq += fmt.Sprintf(" LIMIT %d", limit)
 	}
 
-	if df, e = m.DBload(mq, c.Dialect()); e != nil {
-		panic(e)
+	if df, e = m.DBload(mq, c.Dialect(), d.DFcontext(c.context())); e != nil {
+		return nil, fmt.Errorf("pulling data for column %s: %w", c.Name(), e)
 	}
 
 	var col d.Column
 	if col = df.Column(c.Name()); col == nil {
-		panic(fmt.Errorf("missing column?"))
+		return nil, fmt.Errorf("missing column %s in query result", c.Name())
 	}
 
-	return col.(*m.Col).Data()
+	return col.(*m.Col).Data(), nil
 }
 
 // SQL returns
@@ -114,45 +136,108 @@ func (c *Col) SQL() (snippet string, isFieldName bool) {
 	return c.Dialect().ToName(c.Name()), true
 }
 
+// Len returns the number of rows in the column. It panics if the query fails.
 func (c *Col) Len() int {
 	var (
 		n  int
 		ex error
 	)
-	if n, ex = c.Dialect().RowCount(c.MakeQuery()); ex != nil {
+	if n, ex = c.LenE(); ex != nil {
 		panic(ex)
 	}
 
 	return n
 }
 
-// MakeQuery creates a stand-alone query that will pull the data for this column
+// LenE returns the number of rows in the column.
+func (c *Col) LenE() (int, error) {
+	var (
+		qry string
+		n   int
+		ex  error
+	)
+	if qry, ex = c.MakeQueryE(); ex != nil {
+		return 0, ex
+	}
+
+	if n, ex = c.Dialect().RowCountContext(c.context(), qry); ex != nil {
+		return 0, fmt.Errorf("row count of column %s: %w", c.Name(), ex)
+	}
+
+	return n, nil
+}
+
+// context returns the context of the parent DF.
+func (c *Col) context() context.Context {
+	if c.Parent() == nil {
+		return context.Background()
+	}
+
+	return c.Parent().Context()
+}
+
+// MakeQuery creates a stand-alone query that will pull the data for this column. It panics if the column
+// has no parent.
 func (c *Col) MakeQuery() string {
+	var (
+		qry string
+		e   error
+	)
+	if qry, e = c.MakeQueryE(); e != nil {
+		panic(e)
+	}
+
+	return qry
+}
+
+// MakeQueryE creates a stand-alone query that will pull the data for this column.
+func (c *Col) MakeQueryE() (string, error) {
 	if c.Parent() == nil {
-		panic(fmt.Errorf("nil parent"))
+		return "", fmt.Errorf("column %s has nil parent", c.Name())
 	}
 
-	df := c.Parent().(*DF)
+	df, ok := c.Parent().(*DF)
+	if !ok {
+		return "", fmt.Errorf("parent of column %s is not a *sql.DF", c.Name())
+	}
 
-	with := c.Dialect().WithName()
-	repl := c.Dialect().WithName() // placeholder for the SELECT field
-	parentSQL := df.MakeQuery()
-	ssql := fmt.Sprintf("WITH %s AS (%s) SELECT %s FROM %s", with, parentSQL, repl, with)
+	inParent := c.Name() != "" && df.Column(c.Name()) != nil
 
 	var selectFld string
 	switch {
-	case c.Name() != "" && df.Column(c.Name()) != nil:
+	case inParent:
 		selectFld = c.Dialect().ToName(c.Name())
 	case c.Name() != "" && c.sql != "":
 		selectFld = fmt.Sprintf("%s AS %s", c.sql, c.Dialect().ToName(c.Name()))
-	case c.Name() != "" && c.sql == "" && df.Column(c.Name()) == nil:
+	case c.Name() != "" && c.sql == "":
 		selectFld = fmt.Sprintf("%s AS %s", c.Dialect().ToName(c.Name()), d.RandomLetters(5))
 	default:
 		selectFld = c.Dialect().ToName(c.Name())
 	}
 
-	qry := strings.ReplaceAll(ssql, repl, selectFld)
-	return qry
+	// The column is selected at the level of the parent unless the parent is sorted on a computed column,
+	// which the single field selected would not include.
+	if df.sortsOnSource() {
+		if inParent {
+			return df.MakeQueryE(c.Name())
+		}
+
+		// without a GROUP BY, the column is selected from the same source as the columns of the parent
+		if df.groupBy == "" {
+			return df.query([]string{selectFld}), nil
+		}
+	}
+
+	var (
+		parentSQL string
+		e         error
+	)
+	if parentSQL, e = df.MakeQueryE(); e != nil {
+		return "", e
+	}
+
+	with := c.Dialect().WithName()
+	return fmt.Sprintf("WITH %s AS (%s) SELECT %s FROM %s", with, parentSQL, selectFld, with), nil
 }
 
 func (c *Col) Rename(newName string) error {
@@ -1,10 +1,10 @@
 package sql
 
 import (
-	"database/sql"
 	"fmt"
 	"iter"
 	"maps"
+	"regexp"
 	"strings"
 
 	d "github.com/invertedv/df"
@@ -28,6 +28,9 @@ type DF struct {
 	where   string
 	groupBy string
 
+	depth      int // number of DF queries nested in sourceSQL
+	checkpoint int // if > 0, materialize DFs nested deeper than this
+
 	*d.DFcore
 }
 
@@ -51,7 +54,7 @@ func NewDF(dlct *d.Dialect, input d.HasIter, opts ...d.DFopt) (*DF, error) {
 		}
 
 		tn := dlct.WithName()
-		if e := dlct.Save(tn, "", true, true, inp); e != nil {
+		if e := dlct.SaveContext(d.OptsContext(opts...), tn, "", true, true, inp); e != nil {
 			return nil, e
 		}
 
@@ -82,7 +85,7 @@ func NewDFseq(dlct *d.Dialect, n int, name string, opts ...d.DFopt) (*DF, error)
 
 	dfc, ex := d.NewDFcore([]d.Column{col})
 	if ex != nil {
-		panic(ex)
+		return nil, ex
 	}
 
 	df := &DF{
@@ -121,7 +124,7 @@ func DBload(query string, dlct *d.Dialect, opts ...d.DFopt) (*DF, error) {
 		cols     []d.Column
 	)
 
-	if colNames, colTypes, _, e = dlct.Types(query); e != nil {
+	if colNames, colTypes, _, e = dlct.TypesContext(d.OptsContext(opts...), query); e != nil {
 		return nil, e
 	}
 
@@ -171,43 +174,147 @@ func DBload(query string, dlct *d.Dialect, opts ...d.DFopt) (*DF, error) {
 	return df, nil
 }
 
+// DFcheckpoint sets the maximum nesting depth of the SQL of DFs derived from the DF. Each Join, AppendDF
+// and Interp nests the queries of its inputs. A DF nested deeper than depth is saved to a temp table (see
+// Materialize). If depth is 0, DFs are not checkpointed.
+func DFcheckpoint(depth int) d.DFopt {
+	return func(df d.DC) error {
+		f, ok := df.(*DF)
+		if !ok {
+			return fmt.Errorf("DFcheckpoint requires a *sql.DF")
+		}
+
+		if depth < 0 {
+			return fmt.Errorf("negative depth to DFcheckpoint")
+		}
+
+		f.checkpoint = depth
+
+		return nil
+	}
+}
+
 // ***************** DF - Methods *****************
 
+// AllRows iterates through the rows of f.  It returns the row # and the values of the row.  It panics if the
+// query fails; AllRowsE returns the error.
 func (f *DF) AllRows() iter.Seq2[int, []any] {
 	return func(yield func(int, []any) bool) {
+		rowNum := 0
+		for row, e := range f.AllRowsE() {
+			if e != nil {
+				panic(e)
+			}
 
-		qry := f.MakeQuery()
+			if !yield(rowNum, row) {
+				return
+			}
+
+			rowNum++
+		}
+	}
+}
+
+// AllRowsE iterates through the rows of f, streaming them from the database.  If there's an error, it is
+// yielded with a nil row and the iteration stops.
+func (f *DF) AllRowsE() iter.Seq2[[]any, error] {
+	return func(yield func([]any, error) bool) {
 		var (
-			rows     *sql.Rows
-			row2Read []any
-			e        error
+			qry string
+			dts []d.DataTypes
+			e   error
 		)
-		if rows, row2Read, _, e = f.Dialect().Rows(qry); e != nil {
-			panic(e)
+		if dts, e = f.ColumnTypes(); e != nil {
+			yield(nil, e)
+			return
 		}
-		defer func() {
-			_ = rows.Close()
-		}()
 
-		rowNum := 0
-		for rows.Next() {
-			if ex := rows.Scan(row2Read...); ex != nil {
-				_ = rows.Close()
-				return
+		if qry, e = f.MakeQueryE(); e != nil {
+			yield(nil, e)
+			return
+		}
+
+		stopped := false
+		next := func(row []any) bool {
+			stopped = !yield(row, nil)
+			return !stopped
+		}
+
+		if e = f.Dialect().StreamContext(f.Context(), qry, dts, next); e != nil && !stopped {
+			yield(nil, e)
+		}
+	}
+}
+
+// Batches returns an iterator that yields f as *mem.DF batches of n rows.  The last batch may be shorter.
+// The rows are streamed from the database, so f needn't fit in memory.  If there's an error, it is yielded
+// with a nil *mem.DF and the iteration stops.
+func (f *DF) Batches(n int) iter.Seq2[*m.DF, error] {
+	return func(yield func(*m.DF, error) bool) {
+		if n <= 0 {
+			yield(nil, fmt.Errorf("batch size must be positive"))
+			return
+		}
+
+		var (
+			qry string
+			dts []d.DataTypes
+			e   error
+		)
+		if qry, e = f.MakeQueryE(); e != nil {
+			yield(nil, e)
+			return
+		}
+
+		dts, _ = f.ColumnTypes()
+
+		var (
+			vecs []*d.Vector
+			rows int
+		)
+		// flush yields the rows accumulated in vecs
+		flush := func() bool {
+			var dfOut *m.DF
+			if dfOut, e = f.batch(vecs, rows); e != nil {
+				return false
 			}
 
-			// f.row elements are pointers to interface, remove the "pointer" part
-			row := make([]any, len(row2Read))
-			for ind, x := range row2Read {
-				var z any = *x.(*any)
-				row[ind] = f.Dialect().Convert(z)
+			vecs, rows = nil, 0
+
+			return yield(dfOut, nil)
+		}
+
+		stopped := false
+		add := func(row []any) bool {
+			if vecs == nil {
+				for _, dt := range dts {
+					vecs = append(vecs, d.MakeVector(dt, n))
+				}
 			}
 
-			if !yield(rowNum, row) {
-				return
+			for ind, x := range row {
+				vecs[ind].SetAny(x, rows)
 			}
 
-			rowNum++
+			if rows++; rows == n && !flush() {
+				stopped = true
+				return false
+			}
+
+			return true
+		}
+
+		if es := f.Dialect().StreamContext(f.Context(), qry, dts, add); es != nil && e == nil {
+			e = es
+		}
+
+		// the last, short, batch
+		if e == nil && !stopped && rows > 0 {
+			_ = flush()
+		}
+
+		if e != nil {
+			yield(nil, e)
 		}
 	}
 }
@@ -216,7 +323,13 @@ func (f *DF) AllRows() iter.Seq2[int, []any] {
 // come from the same source.
 func (f *DF) AppendColumn(col d.Column, replace bool) error {
 	// toCol allows us to append constants
-	colx := toCol(f, col)
+	var (
+		colx *Col
+		e    error
+	)
+	if colx, e = toCol(f, col); e != nil {
+		return e
+	}
 
 	if !sameSource(f, colx) {
 		return fmt.Errorf("added column not from same source")
@@ -248,10 +361,18 @@ func (f *DF) AppendDF(dfNew d.DF) (d.DF, error) {
 	}
 
 	var (
-		sqlx string
-		e    error
+		qry1, qry2, sqlx string
+		e                error
 	)
-	if sqlx, e = f.Dialect().Union(f.MakeQuery(), dfNew.(*DF).MakeQuery(), n1...); e != nil {
+	if qry1, e = f.MakeQueryE(); e != nil {
+		return nil, e
+	}
+
+	if qry2, e = dfNew.(*DF).MakeQueryE(); e != nil {
+		return nil, e
+	}
+
+	if sqlx, e = f.Dialect().Union(qry1, qry2, n1...); e != nil {
 		return nil, e
 	}
 
@@ -269,6 +390,10 @@ func (f *DF) AppendDF(dfNew d.DF) (d.DF, error) {
 		return nil, ex
 	}
 
+	if dfOut, eOut = f.nest(dfOut, dfNew.(*DF)); eOut != nil {
+		return nil, eOut
+	}
+
 	return dfOut, nil
 }
 
@@ -277,6 +402,11 @@ func (f *DF) AppendDF(dfNew d.DF) (d.DF, error) {
 //	groupBy - comma-separated list of fields to group on.  If groupBy is empty, then the output will have 1 row.
 //	fns     - functions to calculate on the By groups.
 func (f *DF) By(groupBy string, fns ...string) (d.DF, error) {
+	return f.ByParams(groupBy, nil, fns...)
+}
+
+// ByParams is By with values for the parameters (e.g. @rate) in fns.
+func (f *DF) ByParams(groupBy string, params map[string]any, fns ...string) (d.DF, error) {
 	dfOut := f.Copy().(*DF)
 
 	if groupBy == "" {
@@ -295,10 +425,9 @@ func (f *DF) By(groupBy string, fns ...string) (d.DF, error) {
 	dfOut.groupBy = groupBy
 
 	for _, fn := range fns {
-		if e1 := d.Parse(dfOut, fn); e1 != nil {
+		if e1 := d.ParseParams(dfOut, fn, params); e1 != nil {
 			return nil, e1
 		}
-
 	}
 
 	if e := dfOut.SetParent(); e != nil {
@@ -318,7 +447,7 @@ func (f *DF) By(groupBy string, fns ...string) (d.DF, error) {
 func (f *DF) Categorical(colName string, catMap d.CategoryMap, fuzz int, defaultVal any, levels []any) (d.Column, error) {
 	var col d.Column
 	if col = f.Column(colName); col == nil {
-		return nil, fmt.Errorf("column %s not found", col)
+		return nil, fmt.Errorf("column %s not found", colName)
 	}
 
 	nextInt := 0 // next category level
@@ -357,7 +486,14 @@ func (f *DF) Categorical(colName string, catMap d.CategoryMap, fuzz int, default
 		return nil, e5
 	}
 
-	x := tabl.(*DF).MakeQuery()
+	var (
+		x  string
+		e6 error
+	)
+	if x, e6 = tabl.(*DF).MakeQueryE(); e6 != nil {
+		return nil, e6
+	}
+
 	var (
 		mDF *m.DF
 		e1  error
@@ -407,7 +543,15 @@ func (f *DF) Categorical(colName string, catMap d.CategoryMap, fuzz int, default
 
 		cnts[catVal] += ct
 
-		cond := fmt.Sprintf("%s = %s", colSQL, f.Dialect().ToString(val))
+		var (
+			valSQL string
+			ex     error
+		)
+		if valSQL, ex = f.Dialect().ToStringE(val); ex != nil {
+			return nil, fmt.Errorf("categorical level of %s: %w", col.Name(), ex)
+		}
+
+		cond := fmt.Sprintf("%s = %s", colSQL, valSQL)
 		whens = append(whens, cond)
 		equalTo = append(equalTo, fmt.Sprintf("%d", outVal))
 		if outVal == caseNo {
@@ -426,7 +570,11 @@ func (f *DF) Categorical(colName string, catMap d.CategoryMap, fuzz int, default
 		return nil, ex
 	}
 
-	outCol, _ := NewCol(d.DTcategorical, f.Dialect(), sql1)
+	var outCol *Col
+	if outCol, ex = NewCol(d.DTcategorical, f.Dialect(), sql1); ex != nil {
+		return nil, ex
+	}
+
 	_ = d.ColRawType(col.DataType())(outCol.Core())
 	_ = d.ColCatMap(toMap)(outCol.Core())
 
@@ -452,11 +600,13 @@ func (f *DF) Column(colName string) d.Column {
 func (f *DF) Copy() d.DF {
 	dfCore := f.Core().Copy()
 	dfNew := &DF{
-		sourceSQL: f.sourceSQL,
-		orderBy:   f.orderBy,
-		groupBy:   f.groupBy,
-		where:     f.where,
-		DFcore:    dfCore,
+		sourceSQL:  f.sourceSQL,
+		orderBy:    f.orderBy,
+		groupBy:    f.groupBy,
+		where:      f.where,
+		depth:      f.depth,
+		checkpoint: f.checkpoint,
+		DFcore:     dfCore,
 	}
 	_ = d.DFsetFns(f.Fns())(dfNew)
 
@@ -469,6 +619,19 @@ func (f *DF) DropColumns(colNames ...string) error {
 	return f.Core().DropColumns(colNames...)
 }
 
+// Explain returns the database's EXPLAIN output for the query of f.
+func (f *DF) Explain() (string, error) {
+	var (
+		qry string
+		e   error
+	)
+	if qry, e = f.MakeQueryE(); e != nil {
+		return "", e
+	}
+
+	return f.Dialect().ExplainContext(f.Context(), qry)
+}
+
 func (f *DF) GroupBy() string {
 	return f.groupBy
 }
@@ -491,7 +654,7 @@ func (f *DF) Interp(points d.HasIter, xSfield, xIfield, yfield, outField string)
 		e1  error
 	)
 
-	if idf, e1 = NewDF(f.Dialect(), points, d.DFsetFns(f.Fns())); e1 != nil {
+	if idf, e1 = NewDF(f.Dialect(), points, d.DFsetFns(f.Fns()), d.DFcontext(f.Context())); e1 != nil {
 		return nil, e1
 	}
 
@@ -536,8 +699,17 @@ func (f *DF) Interp(points d.HasIter, xSfield, xIfield, yfield, outField string)
 		return nil, es
 	}
 
-	sQry := favg.(*DF).MakeQuery()
-	iQry := idf.MakeQuery()
+	var (
+		sQry, iQry string
+		eq         error
+	)
+	if sQry, eq = favg.(*DF).MakeQueryE(); eq != nil {
+		return nil, eq
+	}
+
+	if iQry, eq = idf.MakeQueryE(); eq != nil {
+		return nil, eq
+	}
 
 	qry := f.Dialect().Interp(sQry, iQry, xSfield, xIfield, fld, outField)
 
@@ -545,7 +717,11 @@ func (f *DF) Interp(points d.HasIter, xSfield, xIfield, yfield, outField string)
 		df *DF
 		e  error
 	)
-	if df, e = DBload(qry, f.Dialect(), d.DFsetFns(f.Fns())); e != nil {
+	if df, e = DBload(qry, f.Dialect(), d.DFsetFns(f.Fns()), d.DFcontext(f.Context())); e != nil {
+		return nil, e
+	}
+
+	if df, e = f.nest(df, idf); e != nil {
 		return nil, e
 	}
 
@@ -562,7 +738,7 @@ func (f *DF) Join(df d.HasIter, joinOn string) (d.DF, error) {
 		e       error
 	)
 
-	if dfRight, e = NewDF(f.Dialect(), df, d.DFsetFns(f.Fns())); e != nil {
+	if dfRight, e = NewDF(f.Dialect(), df, d.DFsetFns(f.Fns()), d.DFcontext(f.Context())); e != nil {
 		return nil, fmt.Errorf("invalid input to Join")
 	}
 
@@ -591,20 +767,97 @@ func (f *DF) Join(df d.HasIter, joinOn string) (d.DF, error) {
 		rNames = append(rNames, rn)
 	}
 
-	qry := f.Dialect().Join(f.MakeQuery(), dfRight.MakeQuery(), leftNames, rNames, jCols)
+	var (
+		lQry, rQry string
+		eq         error
+	)
+	if lQry, eq = f.MakeQueryE(); eq != nil {
+		return nil, eq
+	}
+
+	if rQry, eq = dfRight.MakeQueryE(); eq != nil {
+		return nil, eq
+	}
+
+	qry := f.Dialect().Join(lQry, rQry, leftNames, rNames, jCols)
 
 	var (
 		outDF *DF
 		e1    error
 	)
-	if outDF, e1 = DBload(qry, f.Dialect(), d.DFsetFns(f.Fns())); e1 != nil {
+	if outDF, e1 = DBload(qry, f.Dialect(), d.DFsetFns(f.Fns()), d.DFcontext(f.Context())); e1 != nil {
+		return nil, e1
+	}
+
+	if outDF, e1 = f.nest(outDF, dfRight); e1 != nil {
 		return nil, e1
 	}
 
 	return outDF, nil
 }
 
+// Materialize saves f to a table and returns a *DF that selects from it.  Queries on the returned *DF
+// start from the table rather than re-running the SQL that produced f.
+//
+//	temp    - if true, the table is a temp table.
+//	orderBy - comma-separated list of fields to use as the table key. If empty, the first column is used.
+func (f *DF) Materialize(temp bool, orderBy string) (*DF, error) {
+	if orderBy == "" {
+		if len(f.ColumnNames()) == 0 {
+			return nil, fmt.Errorf("materialize: DF has no columns")
+		}
+
+		orderBy = f.ColumnNames()[0]
+	}
+
+	tableName := f.Dialect().WithName()
+	if e := f.Dialect().SaveContext(f.Context(), tableName, orderBy, false, temp, f); e != nil {
+		return nil, fmt.Errorf("materialize: %w", e)
+	}
+
+	var (
+		dfOut *DF
+		e     error
+	)
+	if dfOut, e = DBload("SELECT * FROM "+tableName, f.Dialect(), d.DFsetFns(f.Fns()),
+		d.DFcontext(f.Context())); e != nil {
+		return nil, e
+	}
+
+	// categorical columns are saved as their int levels
+	for c := range f.AllColumns() {
+		if c.DataType() != d.DTcategorical {
+			continue
+		}
+
+		col := dfOut.Column(c.Name()).(*Col)
+		_ = d.ColDataType(d.DTcategorical)(col.Core())
+		_ = d.ColRawType(c.Core().RawType())(col.Core())
+		_ = d.ColCatMap(c.CategoryMap())(col.Core())
+	}
+
+	// tables aren't ordered
+	dfOut.orderBy, dfOut.checkpoint = f.orderBy, f.checkpoint
+
+	return dfOut, nil
+}
+
+// MakeQuery returns the query that pulls colNames from f -- all the columns if colNames is empty.
+// It panics if a column is missing.
 func (f *DF) MakeQuery(colNames ...string) string {
+	var (
+		qry string
+		e   error
+	)
+	if qry, e = f.MakeQueryE(colNames...); e != nil {
+		panic(e)
+	}
+
+	return qry
+}
+
+// MakeQueryE returns the query that pulls colNames from f -- all the columns if colNames is empty.
+func (f *DF) MakeQueryE(colNames ...string) (string, error) {
 	var fields []string
 
 	if len(colNames) == 0 {
@@ -614,7 +867,7 @@ func (f *DF) MakeQuery(colNames ...string) string {
 	for ind := range len(colNames) {
 		var cx d.Column
 		if cx = f.Column(colNames[ind]); cx == nil {
-			panic(fmt.Errorf("missing name %s", colNames[ind]))
+			return "", fmt.Errorf("missing name %s in MakeQuery", colNames[ind])
 		}
 
 		var field string
@@ -626,10 +879,30 @@ func (f *DF) MakeQuery(colNames ...string) string {
 		fields = append(fields, field)
 	}
 
-	with := d.RandomLetters(4)
-	qry := fmt.Sprintf("WITH %s AS (%s) SELECT\n%s FROM %s", with, f.sourceSQL, strings.Join(fields, ",\n"), with)
-	if f.where != "" {
-		qry = fmt.Sprintf("%s WHERE %s\n", qry, f.where)
+	return f.query(fields), nil
+}
+
+// query creates the query that selects fields from the source of f.  If the source just selects plain columns
+// from a table, its table and WHERE clause are merged into the query rather than nesting it in a CTE.
+func (f *DF) query(fields []string) string {
+	var qry string
+	where := f.where
+	if table, srcWhere, ok := simpleSource(f.sourceSQL); ok {
+		qry = fmt.Sprintf("SELECT\n%s FROM %s", strings.Join(fields, ",\n"), table)
+
+		switch {
+		case srcWhere != "" && where != "":
+			where = fmt.Sprintf("(%s) AND (%s)", srcWhere, where)
+		case srcWhere != "":
+			where = srcWhere
+		}
+	} else {
+		with := d.RandomLetters(4)
+		qry = fmt.Sprintf("WITH %s AS (%s) SELECT\n%s FROM %s", with, f.sourceSQL, strings.Join(fields, ",\n"), with)
+	}
+
+	if where != "" {
+		qry = fmt.Sprintf("%s WHERE %s\n", qry, where)
 	}
 
 	if f.groupBy != "" {
@@ -643,19 +916,37 @@ func (f *DF) MakeQuery(colNames ...string) string {
 	return qry
 }
 
-// RowCount returns # of rows in f
+// RowCount returns # of rows in f. It panics if the query fails.
 func (f *DF) RowCount() int {
 	var (
 		rowCount int
 		e        error
 	)
-	if rowCount, e = f.Dialect().RowCount(f.MakeQuery()); e != nil {
+	if rowCount, e = f.RowCountE(); e != nil {
 		panic(e)
 	}
 
 	return rowCount
 }
 
+// RowCountE returns # of rows in f.
+func (f *DF) RowCountE() (int, error) {
+	var (
+		qry      string
+		rowCount int
+		e        error
+	)
+	if qry, e = f.MakeQueryE(); e != nil {
+		return 0, e
+	}
+
+	if rowCount, e = f.Dialect().RowCountContext(f.Context(), qry); e != nil {
+		return 0, fmt.Errorf("row count: %w", e)
+	}
+
+	return rowCount, nil
+}
+
 // SetParent sets the parent to f for all the columns in f.
 func (f *DF) SetParent() error {
 	for c := range f.AllColumns() {
@@ -761,7 +1052,12 @@ func (f *DF) Table(cols string) (d.DF, error) {
 
 // Where subsets f to rows where condition is true.
 func (f *DF) Where(condition string) (d.DF, error) {
-	if e := d.Parse(f, "wherec:="+condition); e != nil {
+	return f.WhereParams(condition, nil)
+}
+
+// WhereParams is Where with values for the parameters (e.g. @rate) in condition.
+func (f *DF) WhereParams(condition string, params map[string]any) (d.DF, error) {
+	if e := d.ParseParams(f, "wherec:="+condition, params); e != nil {
 		return nil, e
 	}
 
@@ -787,6 +1083,136 @@ func (f *DF) Where(condition string) (d.DF, error) {
 
 // ***************** Helpers *****************
 
+// batch returns a *mem.DF with the first rows of vecs, which hold the columns of f.
+func (f *DF) batch(vecs []*d.Vector, rows int) (*m.DF, error) {
+	var cols []*m.Col
+	for ind, c := range f.ColumnNames() {
+		v := vecs[ind]
+		if rows < v.Len() {
+			v = d.MakeVector(v.VectorType(), rows)
+			for row := range rows {
+				v.SetAny(vecs[ind].Element(row), row)
+			}
+		}
+
+		var (
+			col *m.Col
+			e   error
+		)
+		if col, e = m.NewCol(v, d.ColName(c)); e != nil {
+			return nil, e
+		}
+
+		// categorical columns hold their int levels
+		if src := f.Column(c); src.DataType() == d.DTcategorical {
+			_ = d.ColDataType(d.DTcategorical)(col)
+			_ = d.ColRawType(src.Core().RawType())(col)
+			_ = d.ColCatMap(src.CategoryMap())(col)
+		}
+
+		cols = append(cols, col)
+	}
+
+	return m.NewDFcol(cols, d.DFcontext(f.Context()))
+}
+
+// nest sets the depth of dfOut, whose SQL nests the queries of f and others.  If dfOut is too deep,
+// it is materialized to a temp table.
+func (f *DF) nest(dfOut *DF, others ...*DF) (*DF, error) {
+	dfOut.depth, dfOut.checkpoint = f.depth, f.checkpoint
+	for _, other := range others {
+		dfOut.depth = max(dfOut.depth, other.depth)
+	}
+
+	dfOut.depth++
+
+	if dfOut.checkpoint == 0 || dfOut.depth <= dfOut.checkpoint {
+		return dfOut, nil
+	}
+
+	return dfOut.Materialize(true, "")
+}
+
+// sortsOnSource returns true if f is not sorted or is sorted only on columns pulled directly from its source.
+func (f *DF) sortsOnSource() bool {
+	if f.orderBy == "" {
+		return true
+	}
+
+	for key := range strings.SplitSeq(f.orderBy, ",") {
+		c := f.Column(strings.TrimSuffix(key, " DESC"))
+		if c == nil {
+			return false
+		}
+
+		if _, isName := c.(*Col).SQL(); !isName {
+			return false
+		}
+	}
+
+	return true
+}
+
+// sqlIdent matches a plain or quoted SQL identifier.
+const sqlIdent = `(?:[A-Za-z_]\w*|"[^"]+"|` + "`[^`]+`" + `)`
+
+// selectPlain matches a query that selects plain columns (or *) from a table, with an optional WHERE clause.
+var selectPlain = regexp.MustCompile(`(?is)^\s*SELECT\s+(?:\*|` + sqlIdent + `(?:\s*,\s*` + sqlIdent + `)*)\s+FROM\s+(` +
+	sqlIdent + `(?:\.` + sqlIdent + `)*)(?:\s+WHERE\s+(.+?))?\s*;?\s*$`)
+
+// clauseKeywords match the clauses that may follow a WHERE clause.
+var clauseKeywords = regexp.MustCompile(`(?i)\b(?:GROUP|HAVING|WINDOW|QUALIFY|ORDER|LIMIT|OFFSET|FETCH|UNION|INTERSECT|EXCEPT|SETTINGS|FORMAT)\b`)
+
+// simpleSource returns the table and WHERE clause of qry if qry just selects plain columns from a table with
+// no renames, aggregation or joins.
+func simpleSource(qry string) (table, where string, ok bool) {
+	m := selectPlain.FindStringSubmatch(qry)
+	if m == nil {
+		return "", "", false
+	}
+
+	// the WHERE clause must not be followed by other clauses
+	if clauseKeywords.MatchString(topLevel(m[2])) {
+		return "", "", false
+	}
+
+	return m[1], m[2], true
+}
+
+// topLevel returns qry with quoted text and the text inside parentheses removed.  It returns "(" if the
+// quotes or parentheses are not balanced.
+func topLevel(qry string) string {
+	var (
+		out   strings.Builder
+		quote rune
+	)
+	depth := 0
+	for _, r := range qry {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"' || r == '`':
+			quote = r
+		case r == '(':
+			depth++
+		case r == ')':
+			if depth--; depth < 0 {
+				return "("
+			}
+		case depth == 0:
+			out.WriteRune(r)
+		}
+	}
+
+	if quote != 0 || depth != 0 {
+		return "("
+	}
+
+	return out.String()
+}
+
 func sameSource(s1, s2 any) bool {
 	sql1, sql2 := "No", "Match"
 	grp1, grp2 := "", ""
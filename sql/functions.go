@@ -11,27 +11,57 @@ import (
 func fnDefs(dlct *d.Dialect) d.Fns {
 	var fns d.Fns
 	for _, v := range dlct.Functions() {
-		if !v.Varying {
-			fns = append(fns,
-				buildFn(v.Name, v.FnDetail, v.Inputs, v.Outputs, v.IsScalar))
-			continue
-		}
-
-		fns = append(fns,
-			varying(v.Name, v.FnDetail, v.Inputs, v.Outputs))
+		fns = append(fns, NewFn(v))
 	}
 
 	return fns
 }
 
+// RegisterFunction adds a SQL function to dlct.  spec has the same format as a line of
+// skeletons/<db>/functions.txt (see d.LoadFunctions). For example:
+//
+//	state:dictGet('loans', 'state', toUInt64(#0)):{int}:string:C:N
+//
+// *DF created from dlct after the call will have access to the function in Parse. To add the
+// function to an existing *DF, use d.DFappendFn(NewFn(spec)).
+func RegisterFunction(dlct *d.Dialect, spec string) error {
+	var (
+		fs *d.FnSpec
+		e  error
+	)
+	if fs, e = d.ParseFnSpec(spec); e != nil {
+		return e
+	}
+
+	return RegisterFnSpec(dlct, fs)
+}
+
+// RegisterFnSpec is the *d.FnSpec version of RegisterFunction.
+func RegisterFnSpec(dlct *d.Dialect, spec *d.FnSpec) error {
+	if dlct == nil {
+		return fmt.Errorf("nil dialect to RegisterFnSpec")
+	}
+
+	return dlct.RegisterFunction(spec)
+}
+
+// NewFn creates a d.Fn from spec.
+func NewFn(spec *d.FnSpec) d.Fn {
+	if spec.Varying {
+		return varying(spec.Name, spec.FnDetail, spec.Inputs, spec.Outputs)
+	}
+
+	return buildFn(spec.Name, spec.FnDetail, spec.Inputs, spec.Outputs, spec.IsScalar)
+}
+
 // varying creates a d.Fn with a varying number of inputs from *.FnSpec. For the most part,
 // this is used to create summary functions across columns (e.g. colSum, colMean).  It restricts the inputs to
 // all having the same type.
 func varying(fnName, sql string, inp [][]d.DataTypes, outp []d.DataTypes) d.Fn {
 	fn := func(info bool, df d.DF, inputs ...d.Column) *d.FnReturn {
 		if info {
-			return &d.FnReturn{Name: fnName, Inputs: nil,
-				Output:  nil,
+			return &d.FnReturn{Name: fnName, Inputs: inp,
+				Output:  outp,
 				Varying: true}
 		}
 
@@ -39,7 +69,11 @@ func varying(fnName, sql string, inp [][]d.DataTypes, outp []d.DataTypes) d.Fn {
 			cols []*Col
 		)
 		for ind := range len(inputs) {
-			col := toCol(df, inputs[ind])
+			col, e := toCol(df, inputs[ind])
+			if e != nil {
+				return &d.FnReturn{Err: e}
+			}
+
 			cols = append(cols, col)
 
 			if ind > 0 {
@@ -54,7 +88,10 @@ func varying(fnName, sql string, inp [][]d.DataTypes, outp []d.DataTypes) d.Fn {
 			i = append(i, inp[ind][0])
 		}
 
-		sqls := getSQL(df, inputs...)
+		sqls, e := getSQL(df, inputs...)
+		if e != nil {
+			return &d.FnReturn{Err: e}
+		}
 
 		ind := d.Position(cols[0].DataType(), i)
 		if ind < 0 {
@@ -85,10 +122,15 @@ func varying(fnName, sql string, inp [][]d.DataTypes, outp []d.DataTypes) d.Fn {
 
 		// we may need to explicitly cast float fields as float
 		if outType == d.DTfloat && df.Dialect().CastFloat() {
-			sqlOut, _ = df.Dialect().CastField(sqlOut, d.DTfloat)
+			if sqlOut, e = df.Dialect().CastField(sqlOut, d.DTfloat); e != nil {
+				return &d.FnReturn{Err: e}
+			}
 		}
 
-		outCol, _ := NewCol(outType, df.Dialect(), sqlOut)
+		var outCol *Col
+		if outCol, e = NewCol(outType, df.Dialect(), sqlOut); e != nil {
+			return &d.FnReturn{Err: e}
+		}
 
 		_ = d.ColParent(df)(outCol)
 		_ = d.ColDialect(df.Dialect())(outCol)
@@ -108,8 +150,18 @@ func buildFn(name, sql string, inp [][]d.DataTypes, outp []d.DataTypes, scalar b
 
 		// glb flags if this is a global query, only meaningful if we have a GROUP BY
 		glb := getGlobal(inputs...) && (df.(*DF).GroupBy() != "")
-		sqls := getSQL(df, inputs...)
-		dts := getDataTypes(df, inputs...)
+		var (
+			sqls []string
+			dts  []d.DataTypes
+			e    error
+		)
+		if sqls, e = getSQL(df, inputs...); e != nil {
+			return &d.FnReturn{Err: e}
+		}
+
+		if dts, e = getDataTypes(df, inputs...); e != nil {
+			return &d.FnReturn{Err: e}
+		}
 
 		var sa []any
 		for j := range len(sqls) {
@@ -151,10 +203,15 @@ func buildFn(name, sql string, inp [][]d.DataTypes, outp []d.DataTypes, scalar b
 
 		// we may need to explicitly cast float fields as float
 		if outType == d.DTfloat && df.Dialect().CastFloat() {
-			sqlOut, _ = df.Dialect().CastField(sqlOut, d.DTfloat)
+			if sqlOut, e = df.Dialect().CastField(sqlOut, d.DTfloat); e != nil {
+				return &d.FnReturn{Err: e}
+			}
 		}
 
-		outCol, _ := NewCol(outType, df.Dialect(), sqlOut)
+		var outCol *Col
+		if outCol, e = NewCol(outType, df.Dialect(), sqlOut); e != nil {
+			return &d.FnReturn{Err: e}
+		}
 
 		_ = d.ColParent(df)(outCol)
 		_ = d.ColDialect(df.Dialect())(outCol)
@@ -172,9 +229,24 @@ func global(info bool, df d.DF, inputs ...d.Column) *d.FnReturn {
 		return &d.FnReturn{Name: "global", Inputs: [][]d.DataTypes{{d.DTunknown}}, Output: []d.DataTypes{d.DTunknown}, IsScalar: false}
 	}
 
-	sqls := getSQL(df, inputs...)
-	dts := getDataTypes(df, inputs...)
-	outCol, _ := NewCol(dts[0], df.Dialect(), sqls[0], d.ColParent(df))
+	var (
+		sqls   []string
+		dts    []d.DataTypes
+		outCol *Col
+		e      error
+	)
+	if sqls, e = getSQL(df, inputs...); e != nil {
+		return &d.FnReturn{Err: e}
+	}
+
+	if dts, e = getDataTypes(df, inputs...); e != nil {
+		return &d.FnReturn{Err: e}
+	}
+
+	if outCol, e = NewCol(dts[0], df.Dialect(), sqls[0], d.ColParent(df)); e != nil {
+		return &d.FnReturn{Err: e}
+	}
+
 	// sends the signal back that this is a global query
 	outCol.gf = true
 
@@ -204,12 +276,15 @@ func toCat(info bool, df d.DF, inputs ...d.Column) *d.FnReturn {
 
 	fuzz := 1
 	if len(inputs) > 1 {
-		f := toAny(inputs[1])
-
 		var (
-			fa any
-			ok bool
+			f, fa any
+			e     error
+			ok    bool
 		)
+		if f, e = toAny(inputs[1]); e != nil {
+			return &d.FnReturn{Err: e}
+		}
+
 		if fa, ok = d.ToDataType(f, d.DTint); !ok {
 			return &d.FnReturn{Err: fmt.Errorf("cannot interpret fuzz as integer in cat")}
 		}
@@ -245,7 +320,14 @@ func applyCat(info bool, df d.DF, inputs ...d.Column) *d.FnReturn {
 
 	newData := inputs[0].(*Col)
 	oldData := inputs[1].(*Col)
-	newVal := toCol(df, inputs[2])
+
+	var (
+		newVal *Col
+		e      error
+	)
+	if newVal, e = toCol(df, inputs[2]); e != nil {
+		return &d.FnReturn{Err: e}
+	}
 
 	if newData.DataType() != oldData.RawType() {
 		return &d.FnReturn{Err: fmt.Errorf("new column must be same type as original data in applyCat")}
@@ -256,10 +338,14 @@ func applyCat(info bool, df d.DF, inputs ...d.Column) *d.FnReturn {
 	}
 
 	var (
-		defaultValue any
-		ok           bool
+		val, defaultValue any
+		ok                bool
 	)
-	if defaultValue, ok = d.ToDataType(toAny(inputs[2]), newVal.DataType()); !ok {
+	if val, e = toAny(inputs[2]); e != nil {
+		return &d.FnReturn{Err: e}
+	}
+
+	if defaultValue, ok = d.ToDataType(val, newVal.DataType()); !ok {
 		return &d.FnReturn{Err: fmt.Errorf("cannot convert default value")}
 	}
 
@@ -272,10 +358,7 @@ func applyCat(info bool, df d.DF, inputs ...d.Column) *d.FnReturn {
 		levels = append(levels, k)
 	}
 
-	var (
-		outCol d.Column
-		e      error
-	)
+	var outCol d.Column
 	if outCol, e = df.(*DF).Categorical(newData.Name(), oldData.CategoryMap(), 0, defaultValue, levels); e != nil {
 		return &d.FnReturn{Err: e}
 	}
@@ -291,20 +374,32 @@ func applyCat(info bool, df d.DF, inputs ...d.Column) *d.FnReturn {
 // toAny expects either a *Col or *d.Scalar input
 // If *Col, it returns the first element of its data
 // If *d.Scalar, it returns its value
-func toAny(x any) any {
+func toAny(x any) (any, error) {
 	if s, ok := x.(*d.Scalar); ok {
-		return s.Data().Element(0)
+		return s.Data().Element(0), nil
 	}
 
 	if s, ok := x.(*Col); ok {
-		return s.Data().Element(0)
+		var (
+			v *d.Vector
+			e error
+		)
+		if v, e = s.DataE(); e != nil {
+			return nil, e
+		}
+
+		if v.Len() == 0 {
+			return nil, fmt.Errorf("column %s has no data", s.Name())
+		}
+
+		return v.Element(0), nil
 	}
 
-	panic(fmt.Errorf("can't make value"))
+	return nil, fmt.Errorf("cannot make a value from %T", x)
 }
 
-// toCol makes a *Col out of x -- this should always be possible
-func toCol(df d.DF, x any) *Col {
+// toCol makes a *Col out of x, which is either a *Col or a *d.Scalar.
+func toCol(df d.DF, x any) (*Col, error) {
 	if c, ok := x.(*Col); ok {
 		if c.Parent() == nil {
 			_ = d.ColParent(df)(c)
@@ -314,48 +409,74 @@ func toCol(df d.DF, x any) *Col {
 			_ = d.ColDialect(df.Dialect())(c)
 		}
 
-		return c
+		return c, nil
 	}
 
-	if s, ok := x.(*d.Scalar); ok {
-		var c *Col
-		xx, _ := s.Data().ElementString(0)
-		fld := *xx
-		if s.DataType() == d.DTstring {
-			fld = df.Dialect().ToString(fld)
-		}
-		// we may need to explicitly cast float fields as float
-		if s.DataType() == d.DTfloat && df.Dialect().CastFloat() {
-			fld, _ = df.Dialect().CastField(fld, d.DTfloat)
-		}
+	s, ok := x.(*d.Scalar)
+	if !ok {
+		return nil, fmt.Errorf("cannot make a column from %T", x)
+	}
 
-		c, _ = NewCol(s.DataType(), nil, fld, d.ColName(s.Name()),
-			d.ColParent(df), d.ColDialect(df.Dialect()))
+	var (
+		xx *string
+		e  error
+	)
+	if xx, e = s.Data().ElementString(0); e != nil {
+		return nil, e
+	}
 
-		return c
+	fld := *xx
+	switch {
+	case s.DataType() == d.DTstring:
+		fld = df.Dialect().ToString(fld)
+	case s.DataType() == d.DTdate:
+		if fld, e = df.Dialect().CastField(df.Dialect().ToString(s.Data().Element(0)), d.DTdate); e != nil {
+			return nil, e
+		}
+	// we may need to explicitly cast float fields as float
+	case s.DataType() == d.DTfloat && df.Dialect().CastFloat():
+		if fld, e = df.Dialect().CastField(fld, d.DTfloat); e != nil {
+			return nil, e
+		}
 	}
 
-	panic(fmt.Errorf("can't make column"))
+	return NewCol(s.DataType(), nil, fld, d.ColName(s.Name()),
+		d.ColParent(df), d.ColDialect(df.Dialect()))
 }
 
-func getSQL(df d.DF, inputs ...d.Column) []string {
+func getSQL(df d.DF, inputs ...d.Column) ([]string, error) {
 	var sOut []string
 	for ind := range len(inputs) {
-		col := toCol(df, inputs[ind])
+		var (
+			col *Col
+			e   error
+		)
+		if col, e = toCol(df, inputs[ind]); e != nil {
+			return nil, e
+		}
+
 		s, _ := col.SQL()
 		sOut = append(sOut, s)
 	}
 
-	return sOut
+	return sOut, nil
 }
 
-func getDataTypes(df d.DF, inputs ...d.Column) []d.DataTypes {
+func getDataTypes(df d.DF, inputs ...d.Column) ([]d.DataTypes, error) {
 	var sOut []d.DataTypes
 	for ind := range len(inputs) {
-		sOut = append(sOut, toCol(df, inputs[ind]).DataType())
+		var (
+			col *Col
+			e   error
+		)
+		if col, e = toCol(df, inputs[ind]); e != nil {
+			return nil, e
+		}
+
+		sOut = append(sOut, col.DataType())
 	}
 
-	return sOut
+	return sOut, nil
 }
 
 // getGlobal returns true if any of the inputs has a gf signal (gf=used global() function)
@@ -4,12 +4,15 @@ import (
 	"database/sql"
 	"fmt"
 	"os"
+	"strings"
+	"testing"
 	"time"
 
 	"github.com/ClickHouse/clickhouse-go/v2"
 	d "github.com/invertedv/df"
 	m "github.com/invertedv/df/mem"
 	_ "github.com/jackc/pgx/stdlib"
+	"github.com/stretchr/testify/assert"
 )
 
 // Create a dataframe from a ClickHouse table.
@@ -542,6 +545,147 @@ func ExampleDF_Where() {
 	// [0 4 8 12 36]
 }
 
+// Add a SQL function to the parser at run time.
+func ExampleRegisterFunction() {
+	const (
+		n          = 5
+		dbProvider = "clickhouse"
+	)
+
+	// ClickHouse connection parameters.
+	user := os.Getenv("user")
+	host := os.Getenv("host")
+	password := os.Getenv("password")
+	db := newConnectCH(host, user, password)
+
+	var (
+		dlct *d.Dialect
+		e0   error
+	)
+	if dlct, e0 = d.NewDialect(dbProvider, db); e0 != nil {
+		panic(e0)
+	}
+
+	// triple takes either an int or a float and returns the same type.
+	if e := RegisterFunction(dlct, "triple:(3 * #0):{int},{float}:int,float:C:N"); e != nil {
+		panic(e)
+	}
+
+	var (
+		df d.DF
+		e1 error
+	)
+	if df, e1 = NewDFseq(dlct, n, "seq"); e1 != nil {
+		panic(e1)
+	}
+
+	if e := d.Parse(df, "x := triple(seq)"); e != nil {
+		panic(e)
+	}
+
+	fmt.Println(df.Column("x").Data().AsAny())
+	// Output:
+	// [0 3 6 9 12]
+}
+
+// plainDF returns a *DF of DTint columns on query.  It does not need a DB connection.
+func plainDF(t *testing.T, query string, names ...string) *DF {
+	dlct, e := d.NewDialect("postgres", nil)
+	assert.Nil(t, e)
+
+	var cols []d.Column
+	for _, name := range names {
+		cc, e1 := d.NewColCore(d.ColDataType(d.DTint), d.ColName(name))
+		assert.Nil(t, e1)
+		cols = append(cols, &Col{ColCore: cc})
+	}
+
+	dfc, e := d.NewDFcore(cols)
+	assert.Nil(t, e)
+
+	df := &DF{sourceSQL: query, DFcore: dfc}
+	_ = d.DFsetFns(StandardFunctions(dlct))(df)
+	_ = d.DFdialect(dlct)(df)
+	assert.Nil(t, df.SetParent())
+
+	return df
+}
+
+func TestMakeQuery_merge(t *testing.T) {
+	// Where -> Where
+	df := plainDF(t, "SELECT * FROM t", "a", "b")
+	dfw, e := df.Where("a > 1")
+	assert.Nil(t, e)
+	dfw, e = dfw.Where("b < 2")
+	assert.Nil(t, e)
+	assert.Equal(t, "SELECT\na,\nb FROM t WHERE (cast((a > 1) AS integer) > 0) AND (cast((b < 2) AS integer) > 0)\n",
+		dfw.(*DF).MakeQuery())
+
+	// projection -> Where
+	df = plainDF(t, `SELECT a, "B" FROM s.t WHERE a IN (1, 2)`, "a", "B")
+	dfw, e = df.Where("B > 0")
+	assert.Nil(t, e)
+	where := ` WHERE (a IN (1, 2)) AND (cast(("B" > 0) AS integer) > 0)` + "\n"
+	assert.Equal(t, "SELECT\na,\n\"B\" FROM s.t"+where, dfw.(*DF).MakeQuery())
+
+	assert.Nil(t, d.Parse(dfw, "c := a + B"))
+	assert.Equal(t, "SELECT\n(a + \"B\") AS c FROM s.t"+where, dfw.Column("c").(*Col).MakeQuery())
+
+	// a column that is not in its parent is selected from the same source
+	cc, e := d.NewColCore(d.ColDataType(d.DTint), d.ColName("x"), d.ColDialect(dfw.Dialect()), d.ColParent(dfw))
+	assert.Nil(t, e)
+	col := &Col{sql: "(2 * a)", ColCore: cc}
+	assert.Equal(t, "SELECT\n(2 * a) AS x FROM s.t"+where, col.MakeQuery())
+
+	// layers that can't be merged are nested
+	for _, qry := range []string{
+		"SELECT a AS b FROM t",
+		"SELECT a, sum(b) AS b FROM t GROUP BY a",
+		"SELECT * FROM t JOIN u ON t.a = u.a",
+		"SELECT * FROM t WHERE a > 0 LIMIT 5",
+		"SELECT DISTINCT a FROM t",
+		"SELECT * FROM t WHERE a = ')' ORDER BY a",
+	} {
+		df = plainDF(t, qry, "a")
+		assert.Contains(t, df.MakeQuery(), "AS ("+qry+") SELECT")
+	}
+}
+
+func TestMakeQuery_sorted(t *testing.T) {
+	df := plainDF(t, "SELECT * FROM t", "a", "b")
+	assert.Nil(t, d.Parse(df, "c := a * 2"))
+
+	// sorted on a source column, a column is pulled directly
+	assert.Nil(t, df.Sort(true, "b"))
+	assert.Equal(t, "SELECT\na FROM t ORDER BY b\n", df.Column("a").(*Col).MakeQuery())
+
+	// sorted on a computed column, the query of the DF is nested
+	assert.Nil(t, df.Sort(true, "c"))
+	qry := df.Column("a").(*Col).MakeQuery()
+	assert.True(t, strings.HasPrefix(qry, "WITH "))
+	assert.Contains(t, qry, "(a * 2) AS c FROM t ORDER BY c\n) SELECT a FROM ")
+}
+
+func TestToCol(t *testing.T) {
+	df := plainDF(t, "SELECT * FROM t", "a")
+
+	sc, e := d.NewScalar("it's")
+	assert.Nil(t, e)
+	col, e := toCol(df, sc)
+	assert.Nil(t, e)
+	sqlx, _ := col.SQL()
+	assert.Equal(t, "'it''s'", sqlx)
+
+	val, e := toAny(sc)
+	assert.Nil(t, e)
+	assert.Equal(t, "it's", val)
+
+	_, e = toCol(df, 3)
+	assert.NotNil(t, e)
+	_, e = toAny(3)
+	assert.NotNil(t, e)
+}
+
 // NewConnect established a new connection to ClickHouse.
 // host is IP address (assumes port 9000), memory is max_memory_usage
 func newConnectCH(host, user, password string) *sql.DB {
@@ -557,6 +557,31 @@ func TestParser(t *testing.T) {
 	}
 }
 
+func TestCheck(t *testing.T) {
+	for _, which := range pkgs("d1") {
+		dfx := loadData(which)
+		for test := range strings.SplitSeq(parserTests, "\n") {
+			vals := strings.Split(strings.ReplaceAll(test, " ", ""), "|")
+			if len(vals) != 4 {
+				continue
+			}
+
+			info, e := d.Check(dfx, "test:="+vals[0])
+			assert.Nil(t, e)
+			assert.Equal(t, d.DTFromString("DT"+vals[2]), info.DataType, vals[0])
+			assert.Nil(t, dfx.Column("test"))
+
+			if _, isSQL := dfx.(*s.DF); isSQL {
+				assert.NotEmpty(t, info.SQL)
+			}
+		}
+
+		if dlct := dfx.Dialect(); dlct != nil {
+			_ = dlct.Close()
+		}
+	}
+}
+
 // TODO: optional name to DFSeq for the column
 
 /*
@@ -1,16 +1,19 @@
 package testing
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"sort"
 	"strings"
 	"testing"
+	"time"
 
 	d "github.com/invertedv/df"
 	m "github.com/invertedv/df/mem"
 	s "github.com/invertedv/df/sql"
 	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace/noop"
 )
 
 func TestNewDFmem(t *testing.T) {
@@ -381,6 +384,466 @@ func TestSQLsave(t *testing.T) {
 	}
 }
 
+// hostile are strings that break SQL if they aren't escaped
+var hostile = []string{"O'Brien", `back\slash`, `\'`, "''", "a'); DROP TABLE temp; --", "line\nbreak", `trailing\`}
+
+func TestToString(t *testing.T) {
+	dch, _ := d.NewDialect(ch, nil)
+	dpg, _ := d.NewDialect(pg, nil)
+
+	assert.Equal(t, `'O\'Brien'`, dch.ToString("O'Brien"))
+	assert.Equal(t, `'back\\slash'`, dch.ToString(`back\slash`))
+	assert.Equal(t, `'\\\''`, dch.ToString(`\'`))
+
+	assert.Equal(t, `'O''Brien'`, dpg.ToString("O'Brien"))
+	assert.Equal(t, `E'back\\slash'`, dpg.ToString(`back\slash`))
+	assert.Equal(t, `E'\\'''`, dpg.ToString(`\'`))
+
+	assert.Equal(t, "'2024-01-02'", dpg.ToString(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, "3", dch.ToString(3))
+}
+
+func TestErrorForms(t *testing.T) {
+	dpg, _ := d.NewDialect(pg, nil)
+
+	_, e := dpg.ToStringE([]int{1})
+	assert.NotNil(t, e)
+	assert.Panics(t, func() { dpg.ToString([]int{1}) })
+
+	x, e := dpg.ConvertE(int32(3))
+	assert.Nil(t, e)
+	assert.Equal(t, 3, x)
+	_, e = dpg.ConvertE(struct{}{})
+	assert.NotNil(t, e)
+
+	_, e = d.MakeVectorE(d.DTunknown, 3)
+	assert.NotNil(t, e)
+	v, e := d.MakeVectorE(d.DTfloat, 3)
+	assert.Nil(t, e)
+	assert.Equal(t, 3, v.Len())
+
+	for _, which := range pkgs("d1") {
+		dfx := loadData(which)
+		sdf, ok := dfx.(*s.DF)
+		if !ok {
+			continue
+		}
+
+		_, e = sdf.MakeQueryE("x", "nope")
+		assert.NotNil(t, e)
+
+		n, e := sdf.RowCountE()
+		assert.Nil(t, e)
+		assert.Equal(t, dfx.RowCount(), n)
+
+		rows := 0
+		for row, e := range sdf.AllRowsE() {
+			assert.Nil(t, e)
+			assert.Equal(t, len(sdf.ColumnNames()), len(row))
+			rows++
+		}
+		assert.Equal(t, n, rows)
+
+		exist, e := sdf.Dialect().ExistsContext(context.Background(), "nope")
+		assert.Nil(t, e)
+		assert.False(t, exist)
+	}
+}
+
+func TestSQLsave_hostile(t *testing.T) {
+	var ks []int
+	for ind := range len(hostile) {
+		ks = append(ks, ind)
+	}
+
+	k, _ := m.NewCol(ks, d.ColName("k"))
+	str, _ := m.NewCol(hostile, d.ColName("s"))
+	dfh, e := m.NewDFcol([]*m.Col{k, str})
+	assert.Nil(t, e)
+
+	for _, which := range pkgs("d1") {
+		dfx := loadData(which)
+		dlct := dfx.Dialect()
+
+		// saved with IterSave
+		e1 := dlct.Save("temp", "k", true, true, dfh)
+		assert.Nil(t, e1)
+
+		dfy, e2 := s.DBload("SELECT * FROM temp ORDER BY k", dlct)
+		assert.Nil(t, e2)
+		assert.Equal(t, hostile, dfy.Column("s").Data().AsAny())
+
+		// literals within SQL
+		for ind, h := range hostile {
+			dfw, e3 := dfy.WhereParams("s == @h", map[string]any{"h": h})
+			assert.Nil(t, e3)
+			assert.Equal(t, []int{ind}, dfw.Column("k").Data().AsAny())
+		}
+
+		if dlct := dfx.Dialect(); dlct != nil {
+			_ = dlct.Close()
+		}
+	}
+}
+
+func TestSQLsave_progress(t *testing.T) {
+	for _, which := range pkgs("d1") {
+		dfx := loadData(which)
+		dlct := dfx.Dialect()
+
+		var reports []int
+		_ = d.DialectProgress(4, func(tableName string, rows int, done bool) {
+			assert.Equal(t, "temp", tableName)
+			reports = append(reports, rows)
+		})(dlct)
+
+		// mem DF's are saved row by row
+		dfm, e := m.NewDF(dfx)
+		assert.Nil(t, e)
+		e1 := dlct.Save("temp", "k", true, true, dfm)
+		assert.Nil(t, e1)
+		assert.Equal(t, []int{4, 6}, reports)
+
+		dfy, e2 := m.DBload("SELECT * FROM temp ORDER BY k", dlct)
+		assert.Nil(t, e2)
+		assert.Equal(t, dfm.Column("dt").Data().AsAny(), dfy.Column("dt").Data().AsAny())
+
+		if dlct := dfx.Dialect(); dlct != nil {
+			_ = dlct.Close()
+		}
+	}
+}
+
+func TestSQLlogger(t *testing.T) {
+	for _, which := range pkgs("d1") {
+		dfx := loadData(which)
+		dlct := dfx.Dialect()
+
+		var events []*d.QueryEvent
+		assert.Nil(t, d.DialectLogger(func(ev *d.QueryEvent) { events = append(events, ev) })(dlct))
+		assert.Nil(t, d.DialectTracer(noop.NewTracerProvider().Tracer("df"))(dlct))
+
+		qry := fmt.Sprintf("SELECT %s AS seq", dlct.Seq(10))
+		_, _, _, e := dlct.Load(qry)
+		assert.Nil(t, e)
+
+		ops := make(map[string]*d.QueryEvent)
+		for _, ev := range events {
+			ops[ev.Op] = ev
+		}
+
+		for _, op := range []string{"Types", "Load"} {
+			assert.Contains(t, ops, op)
+		}
+
+		assert.Equal(t, 10, ops["Load"].Rows)
+		assert.Equal(t, qry, ops["Load"].SQL)
+		assert.Nil(t, ops["Load"].Err)
+
+		events = nil
+		_, _, _, e = dlct.Load("SELECT nope FROM nowhere")
+		assert.NotNil(t, e)
+		assert.NotNil(t, events[len(events)-1].Err)
+
+		if dlct := dfx.Dialect(); dlct != nil {
+			_ = dlct.Close()
+		}
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	for _, which := range pkgs("d1") {
+		dfx := loadData(which)
+		dlct := dfx.Dialect()
+
+		schema, tableName := "", "d1"
+		if dlct.DialectName() == ch {
+			schema, tableName = "testing", "testing.d1"
+		}
+
+		tables, e := dlct.Tables(schema)
+		assert.Nil(t, e)
+		assert.Contains(t, tables, "d1")
+
+		names, types, e := dlct.Describe(tableName)
+		assert.Nil(t, e)
+		assert.Equal(t, dfx.ColumnNames(), names)
+		for ind, name := range names {
+			assert.Equal(t, dfx.Column(name).DataType(), types[ind])
+		}
+
+		// the row estimate of Postgres is as of the last ANALYZE
+		if dlct.DialectName() == pg {
+			_, e = dlct.DB().Exec("ANALYZE " + tableName)
+			assert.Nil(t, e)
+		}
+
+		stats, e := m.TableStats(dlct, schema)
+		assert.Nil(t, e)
+		assert.Equal(t, []string{"name", "rows", "columns", "bytes"}, stats.ColumnNames())
+		dfs, e := stats.Where("name == 'd1'")
+		assert.Nil(t, e)
+		assert.Equal(t, []int{dfx.RowCount()}, dfs.Column("rows").Data().AsAny())
+		assert.Equal(t, []int{len(names)}, dfs.Column("columns").Data().AsAny())
+		assert.Greater(t, dfs.Column("bytes").Data().Element(0), 0)
+
+		_, _, e = dlct.Describe("not_a_table")
+		assert.NotNil(t, e)
+
+		if dlct := dfx.Dialect(); dlct != nil {
+			_ = dlct.Close()
+		}
+	}
+}
+
+// decimal has the method ConvertTo uses to recognize decimals
+type decimal float64
+
+func (x decimal) InexactFloat64() float64 { return float64(x) }
+
+// uuid is a Stringer
+type uuid [2]byte
+
+func (x uuid) String() string { return fmt.Sprintf("%x-%x", x[0], x[1]) }
+
+func TestConvertTo(t *testing.T) {
+	dlct, _ := d.NewDialect(pg, nil, d.DialectDefaultInt(-1))
+
+	var (
+		i8   int8 = 3
+		nil8 *int8
+	)
+	tests := []struct {
+		val  any
+		dt   d.DataTypes
+		want any
+	}{
+		{decimal(1.5), d.DTfloat, 1.5},
+		{"1.25", d.DTfloat, 1.25},
+		{uuid{10, 11}, d.DTstring, "a-b"},
+		{&i8, d.DTint, 3},
+		{nil8, d.DTint, -1},
+		{nil, d.DTstring, ""},
+		{true, d.DTint, 1},
+		{[]int8{1, 2}, d.DTstring, "[1,2]"},
+		{map[string]int{"a": 1}, d.DTstring, `{"a":1}`},
+		{int64(4), d.DTcategorical, 4},
+		{"20240102", d.DTdate, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tst := range tests {
+		x, e := dlct.ConvertTo(tst.val, tst.dt)
+		assert.Nil(t, e)
+		assert.Equal(t, tst.want, x)
+	}
+
+	_, e := dlct.ConvertTo(struct{}{}, d.DTfloat)
+	assert.NotNil(t, e)
+	_, e = dlct.ConvertTo("abc", d.DTfloat)
+	assert.NotNil(t, e)
+
+	assert.NotNil(t, d.DialectColumnType("x", d.DTunknown)(dlct))
+	assert.NotNil(t, d.DialectFallback(d.DTcategorical)(dlct))
+}
+
+func TestDBtypes(t *testing.T) {
+	qrys := map[string]string{
+		ch: "SELECT toDecimal64(1.25, 2) AS dec, toUUID('00000000-0000-0000-0000-000000000001') AS id, " +
+			"[toInt8(1), 2] AS arr, toDateTime64('2024-01-02 03:04:05', 3, 'UTC') AS ts, " +
+			"toNullable(toInt32(7)) AS n, toLowCardinality('a') AS lc, toInt32(5) AS forced, " +
+			"CAST(NULL AS LowCardinality(Nullable(String))) AS lcn",
+		pg: "SELECT 1.25::numeric(6,2) AS dec, '00000000-0000-0000-0000-000000000001'::uuid AS id, " +
+			"ARRAY[1,2] AS arr, '2024-01-02 03:04:05'::timestamp AS ts, " +
+			"NULL::integer AS n, 'a'::varchar AS lc, 5 AS forced, NULL::varchar AS lcn",
+	}
+
+	for _, which := range pkgs("d1") {
+		dfx := loadData(which)
+		dlct := dfx.Dialect()
+		assert.Nil(t, d.DialectColumnType("forced", d.DTstring)(dlct))
+
+		memData, names, types, e := dlct.Load(qrys[dlct.DialectName()])
+		assert.Nil(t, e)
+		assert.Equal(t, []string{"dec", "id", "arr", "ts", "n", "lc", "forced", "lcn"}, names)
+		assert.Equal(t, []d.DataTypes{d.DTfloat, d.DTstring, d.DTstring, d.DTdate, d.DTint, d.DTstring, d.DTstring, d.DTstring}, types)
+
+		assert.Equal(t, 1.25, memData[0].Element(0))
+		assert.Equal(t, "00000000-0000-0000-0000-000000000001", memData[1].Element(0))
+		assert.Equal(t, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), memData[3].Element(0))
+		assert.Equal(t, "a", memData[5].Element(0))
+		assert.Equal(t, "5", memData[6].Element(0))
+
+		if dlct := dfx.Dialect(); dlct != nil {
+			_ = dlct.Close()
+		}
+	}
+}
+
+func TestMaterialize(t *testing.T) {
+	for _, which := range pkgs("d1") {
+		dfx := loadData(which)
+		sdf, ok := dfx.(*s.DF)
+		if !ok {
+			continue
+		}
+
+		dfw, e := sdf.Where("y > 0")
+		assert.Nil(t, e)
+		assert.Nil(t, dfw.Sort(true, "k"))
+
+		dfm, e := dfw.(*s.DF).Materialize(true, "k")
+		assert.Nil(t, e)
+		assert.True(t, strings.HasPrefix(dfm.SourceSQL(), "SELECT * FROM "))
+		assert.Equal(t, dfw.RowCount(), dfm.RowCount())
+		for c := range dfw.AllColumns() {
+			assert.Equal(t, c.Data().AsAny(), dfm.Column(c.Name()).Data().AsAny())
+		}
+
+		// checkpoint after every Join
+		assert.Nil(t, s.DFcheckpoint(1)(sdf))
+		keys := sdf.Copy()
+		assert.Nil(t, keys.KeepColumns("k"))
+		dfj, e := sdf.Join(keys, "k")
+		assert.Nil(t, e)
+		dfj, e = dfj.Join(keys, "k")
+		assert.Nil(t, e)
+		assert.True(t, strings.HasPrefix(dfj.(*s.DF).SourceSQL(), "SELECT * FROM "))
+		assert.Equal(t, dfx.RowCount(), dfj.RowCount())
+
+		if dlct := dfx.Dialect(); dlct != nil {
+			_ = dlct.Close()
+		}
+	}
+}
+
+func TestPrettySQL(t *testing.T) {
+	qry := "WITH abcd AS (SELECT * FROM t WHERE s = 'a  b' AND u IN (1,2)) SELECT\nk,\ncount(x) AS n FROM abcd " +
+		"LEFT JOIN v ON abcd.k = v.k GROUP BY k ORDER BY k"
+	exp := `WITH abcd AS (
+  SELECT *
+  FROM t
+  WHERE s = 'a  b' AND u IN (1, 2)
+)
+SELECT k,
+  count(x) AS n
+FROM abcd
+LEFT JOIN v ON abcd.k = v.k
+GROUP BY k
+ORDER BY k`
+
+	pretty := d.PrettySQL(qry)
+	assert.Equal(t, exp, pretty)
+	assert.Equal(t, pretty, d.PrettySQL(pretty))
+}
+
+func TestExplain(t *testing.T) {
+	for _, which := range pkgs("d1") {
+		dfx := loadData(which)
+		sdf, ok := dfx.(*s.DF)
+		if !ok {
+			continue
+		}
+
+		// a DF on a table selects from it directly
+		qry := sdf.MakeQuery()
+		assert.False(t, strings.HasPrefix(qry, "WITH"))
+
+		dfw, e := sdf.Where("y > 0")
+		assert.Nil(t, e)
+		plan, e := dfw.(*s.DF).Explain()
+		assert.Nil(t, e)
+		assert.NotEqual(t, "", plan)
+
+		if dlct := dfx.Dialect(); dlct != nil {
+			_ = dlct.Close()
+		}
+	}
+}
+
+func TestStream(t *testing.T) {
+	for _, which := range pkgs("d1") {
+		dfx := loadData(which)
+		sdf, ok := dfx.(*s.DF)
+		if !ok {
+			continue
+		}
+
+		assert.Nil(t, d.DialectFetchSize(2)(sdf.Dialect()))
+
+		// AllRows streams the rows
+		var ks []any
+		for _, row := range sdf.AllRows() {
+			ks = append(ks, row[0])
+		}
+
+		assert.Equal(t, sdf.RowCount(), len(ks))
+
+		// stop early
+		n := 0
+		for range sdf.AllRows() {
+			if n++; n == 3 {
+				break
+			}
+		}
+
+		assert.Equal(t, 3, n)
+
+		// batches of 4 rows
+		var (
+			sizes []int
+			kb    []int
+		)
+		for batch, e := range sdf.Batches(4) {
+			assert.Nil(t, e)
+			sizes = append(sizes, batch.RowCount())
+			k, _ := batch.Column("k").Data().AsInt()
+			kb = append(kb, k...)
+		}
+
+		kAll, _ := sdf.Column("k").Data().AsInt()
+		assert.Equal(t, kAll, kb)
+		assert.Equal(t, []int{4, sdf.RowCount() - 4}, sizes)
+
+		for _, e := range sdf.Batches(0) {
+			assert.NotNil(t, e)
+		}
+
+		if dlct := dfx.Dialect(); dlct != nil {
+			_ = dlct.Close()
+		}
+	}
+}
+
+func TestContext(t *testing.T) {
+	for _, which := range pkgs("d1") {
+		dfx := loadData(which)
+		dlct := dfx.Dialect()
+		qry := fmt.Sprintf("SELECT %s AS seq", dlct.Seq(10))
+
+		_, _, _, e := dlct.LoadContext(context.Background(), qry)
+		assert.Nil(t, e)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, _, _, e = dlct.LoadContext(ctx, qry)
+		assert.ErrorIs(t, e, context.Canceled)
+
+		e = dlct.SaveContext(ctx, "temp", "k", true, true, dfx)
+		assert.NotNil(t, e)
+
+		_, e = m.DBload(qry, dlct, d.DFcontext(ctx))
+		assert.NotNil(t, e)
+
+		_, e = s.DBload(qry, dlct, d.DFcontext(ctx))
+		assert.NotNil(t, e)
+
+		if dlct := dfx.Dialect(); dlct != nil {
+			_ = dlct.Close()
+		}
+	}
+}
+
 func TestFileSave(t *testing.T) {
 	const coln = "b"
 
@@ -491,6 +954,20 @@ func Test_Sort(t *testing.T) {
 	}
 }
 
+func Test_SortComputed(t *testing.T) {
+	for _, which := range pkgs("d1") {
+		dfx := loadData(which)
+		assert.Nil(t, d.Parse(dfx, "c := y * 2"))
+		assert.Nil(t, dfx.Sort(true, "c"))
+
+		assert.Equal(t, []int{-5, 1, 1, 4, 5, 6}, dfx.Column("y").Data().AsAny())
+
+		if dlct := dfx.Dialect(); dlct != nil {
+			_ = dlct.Close()
+		}
+	}
+}
+
 func TestWhere(t *testing.T) {
 	for _, which := range pkgs("d1") {
 		// via methods
@@ -509,6 +986,28 @@ func TestWhere(t *testing.T) {
 	}
 }
 
+func TestWhere_params(t *testing.T) {
+	params := map[string]any{"y": -5, "z": "20060102", "dt": time.Date(2022, 12, 31, 0, 0, 0, 0, time.UTC), "quote": "it's"}
+	for _, which := range pkgs("d1") {
+		dfx := loadData(which)
+		dfOut, e := dfx.WhereParams("y == @y || z == @z", params)
+		assert.Nil(t, e)
+		assert.Equal(t, []int{2, 3, 4}, dfOut.Column("k").Data().AsAny())
+
+		dfOut, e = dfx.WhereParams("dt >= $dt", params)
+		assert.Nil(t, e)
+		assert.Equal(t, []int{1, 5}, dfOut.Column("k").Data().AsAny())
+
+		dfOut, e = dfx.WhereParams("z != @quote", params)
+		assert.Nil(t, e)
+		assert.Equal(t, 6, dfOut.RowCount())
+
+		if dlct := dfx.Dialect(); dlct != nil {
+			_ = dlct.Close()
+		}
+	}
+}
+
 func TestAppendDF(t *testing.T) {
 	for _, which := range pkgs("d1") {
 		dfx := loadData(which)
@@ -26,19 +26,32 @@ func NewVector(data any, dt DataTypes) (*Vector, error) {
 	return &Vector{dt: dt, data: v}, nil
 }
 
-// MakeVector returns a *Vector with data of type dt and length n.
+// MakeVector returns a *Vector with data of type dt and length n. It panics if dt is not supported.
 func MakeVector(dt DataTypes, n int) *Vector {
+	var (
+		v *Vector
+		e error
+	)
+	if v, e = MakeVectorE(dt, n); e != nil {
+		panic(e)
+	}
+
+	return v
+}
+
+// MakeVectorE returns a *Vector with data of type dt and length n.
+func MakeVectorE(dt DataTypes, n int) (*Vector, error) {
 	switch dt {
 	case DTfloat:
-		return &Vector{dt: dt, data: make([]float64, n)}
+		return &Vector{dt: dt, data: make([]float64, n)}, nil
 	case DTint, DTcategorical:
-		return &Vector{dt: DTint, data: make([]int, n)}
+		return &Vector{dt: DTint, data: make([]int, n)}, nil
 	case DTstring:
-		return &Vector{dt: dt, data: make([]string, n)}
+		return &Vector{dt: dt, data: make([]string, n)}, nil
 	case DTdate:
-		return &Vector{dt: dt, data: make([]time.Time, n)}
+		return &Vector{dt: dt, data: make([]time.Time, n)}, nil
 	default:
-		panic(fmt.Errorf("cannot make Vector with data type %s", dt))
+		return nil, fmt.Errorf("cannot make Vector with data type %s", dt)
 	}
 }
 
@@ -286,6 +299,39 @@ func (v *Vector) SetAny(val any, indx int) {
 	}
 }
 
+// push appends val, which must have the type of v.  Unlike Append, it doesn't convert val.
+func (v *Vector) push(val any) error {
+	ok := false
+	switch x := val.(type) {
+	case float64:
+		var data []float64
+		if data, ok = v.data.([]float64); ok {
+			v.data = append(data, x)
+		}
+	case int:
+		var data []int
+		if data, ok = v.data.([]int); ok {
+			v.data = append(data, x)
+		}
+	case string:
+		var data []string
+		if data, ok = v.data.([]string); ok {
+			v.data = append(data, x)
+		}
+	case time.Time:
+		var data []time.Time
+		if data, ok = v.data.([]time.Time); ok {
+			v.data = append(data, x)
+		}
+	}
+
+	if !ok {
+		return fmt.Errorf("cannot push %T onto vector of type %v", val, v.VectorType())
+	}
+
+	return nil
+}
+
 // SetDate sets the indx'th element to val.  Does not attempt conversion.
 func (v *Vector) SetDate(val time.Time, indx int) error {
 	if v.VectorType() != DTdate {